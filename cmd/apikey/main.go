@@ -0,0 +1,52 @@
+// Command apikey issues the first API key for AUTH_MODE=api_key.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/application"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/infrastructure/dynamodb"
+)
+
+func main() {
+	name := flag.String("name", "bootstrap", "key name")
+	apps := flag.String("apps", domain.APIKeyAllApps, "comma-separated application IDs, or * for all")
+	expiresIn := flag.Duration("expires-in", 90*24*time.Hour, "key lifetime")
+	flag.Parse()
+
+	tableName, region := os.Getenv("TABLE_NAME"), os.Getenv("AWS_REGION")
+	if tableName == "" || region == "" {
+		fmt.Fprintln(os.Stderr, "TABLE_NAME and AWS_REGION are required")
+		os.Exit(1)
+	}
+	// There is no request segment to attach DynamoDB subsegments to.
+	xray.Configure(xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy()})
+	ctx := context.Background()
+	client, err := dynamodb.NewClient(ctx, region, tableName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize dynamodb client:", err)
+		os.Exit(1)
+	}
+	svc := application.NewAPIKeyService(dynamodb.NewAPIKeyRepository(client))
+	issued, err := svc.Create(ctx, domain.APIKey{
+		Name:      *name,
+		AppIDs:    strings.Split(*apps, ","),
+		ExpiresAt: time.Now().Add(*expiresIn),
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create api key:", err)
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(issued)
+}
@@ -2,26 +2,39 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
 	"github.com/aws/aws-xray-sdk-go/xray"
 	"github.com/labstack/echo/v4"
 	adaptermiddleware "rbac-project/internal/adapters/http/middleware"
 	adapterlogger "rbac-project/internal/adapters/logger"
 	"rbac-project/internal/application"
+	"rbac-project/internal/domain"
 	"rbac-project/internal/infrastructure/auth"
 	"rbac-project/internal/infrastructure/dynamodb"
 	httpiface "rbac-project/internal/interfaces/http"
+	"rbac-project/internal/ports"
 )
 
 type config struct {
 	TableName         string
 	Region            string
 	UserPoolID        string
+	CognitoClientIDs  []string
+	CognitoTokenUses  []string
+	OIDCIssuerURL     string
+	OIDCAudiences     []string
+	OIDCUserIDClaim   string
 	AuthMode          adaptermiddleware.Mode
 	AuthorizeTestMode string
 	Port              string
+	SweepInterval     time.Duration
 }
 
 func loadConfig() (config, error) {
@@ -41,15 +54,45 @@ func loadConfig() (config, error) {
 		AuthorizeTestMode: os.Getenv("AUTHORIZE_TEST_MODE"),
 		Port:              port,
 	}
+	if raw := os.Getenv("ASSIGNMENT_SWEEP_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err != nil || interval <= 0 {
+			return config{}, errors.New("ASSIGNMENT_SWEEP_INTERVAL must be a positive duration")
+		}
+		cfg.SweepInterval = interval
+	}
 	if cfg.TableName == "" || cfg.Region == "" {
 		return config{}, errors.New("missing required environment variables")
 	}
 	if cfg.AuthMode == adaptermiddleware.ModeCognito && cfg.UserPoolID == "" {
 		return config{}, errors.New("COGNITO_USER_POOL_ID is required for cognito auth mode")
 	}
+	cfg.CognitoClientIDs = splitList(os.Getenv("COGNITO_CLIENT_IDS"))
+	cfg.CognitoTokenUses = splitList(os.Getenv("COGNITO_TOKEN_USE"))
+	for _, tokenUse := range cfg.CognitoTokenUses {
+		if tokenUse != auth.TokenUseAccess && tokenUse != auth.TokenUseID {
+			return config{}, errors.New("COGNITO_TOKEN_USE must list access and/or id")
+		}
+	}
+	cfg.OIDCIssuerURL = os.Getenv("OIDC_ISSUER_URL")
+	cfg.OIDCAudiences = splitList(os.Getenv("OIDC_AUDIENCES"))
+	cfg.OIDCUserIDClaim = os.Getenv("OIDC_USER_ID_CLAIM")
+	if cfg.AuthMode == adaptermiddleware.ModeOIDC && (cfg.OIDCIssuerURL == "" || len(cfg.OIDCAudiences) == 0) {
+		return config{}, errors.New("OIDC_ISSUER_URL and OIDC_AUDIENCES are required for oidc auth mode")
+	}
 	return cfg, nil
 }
 
+func splitList(raw string) []string {
+	var out []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func main() {
 	logger := adapterlogger.New()
 
@@ -69,18 +112,46 @@ func main() {
 	roleRepo := dynamodb.NewRoleRepository(ddbClient)
 	permRepo := dynamodb.NewPermissionRepository(ddbClient)
 	userRepo := dynamodb.NewUserRoleRepository(ddbClient)
+	groupRepo := dynamodb.NewGroupRepository(ddbClient)
+	apiKeyRepo := dynamodb.NewAPIKeyRepository(ddbClient)
+	mappingRepo := dynamodb.NewClaimMappingRepository(ddbClient)
 
-	appSvc := application.NewApplicationService(appRepo, logger)
-	roleSvc := application.NewRoleService(roleRepo, logger)
-	permSvc := application.NewPermissionService(permRepo, logger)
-	userSvc := application.NewUserService(userRepo, roleRepo, logger)
-	authorizationSvc := application.NewAuthorizationService(userRepo, roleRepo, logger)
+	appSvc := application.NewApplicationService(appRepo, userRepo, logger)
+	roleSvc := application.NewRoleService(roleRepo, userRepo, permRepo, appRepo, groupRepo, mappingRepo, logger)
+	permSvc := application.NewPermissionService(permRepo, roleRepo, logger)
+	userSvc := application.NewUserService(userRepo, roleRepo, groupRepo, mappingRepo, logger)
+	authorizationSvc := application.NewAuthorizationService(userRepo, roleRepo, groupRepo, mappingRepo, logger)
+	groupSvc := application.NewGroupService(groupRepo, roleRepo, logger)
+	apiKeySvc := application.NewAPIKeyService(apiKeyRepo, logger)
+	mappingSvc := application.NewClaimMappingService(mappingRepo, roleRepo, userRepo, logger)
 
 	var cognitoHandler echo.MiddlewareFunc
 	if cfg.AuthMode == adaptermiddleware.ModeCognito {
-		cognitoHandler = auth.NewCognitoMiddleware(cfg.UserPoolID, cfg.Region).Handler
+		cognitoHandler = auth.NewCognitoMiddleware(auth.CognitoConfig{
+			UserPoolID: cfg.UserPoolID,
+			Region:     cfg.Region,
+			ClientIDs:  cfg.CognitoClientIDs,
+			TokenUses:  cfg.CognitoTokenUses,
+		}).Handler
+	}
+	var apiKeyHandler echo.MiddlewareFunc
+	if cfg.AuthMode == adaptermiddleware.ModeAPIKey {
+		apiKeyHandler = adaptermiddleware.APIKeyMiddleware(apiKeySvc)
+	}
+	var oidcHandler echo.MiddlewareFunc
+	if cfg.AuthMode == adaptermiddleware.ModeOIDC {
+		oidc, err := auth.NewOIDCMiddleware(context.Background(), auth.OIDCConfig{
+			IssuerURL:   cfg.OIDCIssuerURL,
+			Audiences:   cfg.OIDCAudiences,
+			UserIDClaim: cfg.OIDCUserIDClaim,
+		})
+		if err != nil {
+			logger.Error(context.Background(), "failed to initialize oidc middleware", "error", err)
+			os.Exit(1)
+		}
+		oidcHandler = oidc.Handler
 	}
-	authMiddleware, err := adaptermiddleware.AuthMiddleware(cognitoHandler)
+	authMiddleware, err := adaptermiddleware.AuthMiddleware(cognitoHandler, apiKeyHandler, oidcHandler)
 	if err != nil {
 		logger.Error(context.Background(), "failed to initialize auth middleware", "error", err)
 		os.Exit(1)
@@ -90,6 +161,10 @@ func main() {
 		XRay:          adaptermiddleware.XRayMiddleware("rbac-http"),
 		RequestLogger: adaptermiddleware.RequestLogger(logger),
 	}
+	// Without authentication there is no caller to check.
+	if cfg.AuthMode != adaptermiddleware.ModeNone {
+		mw.Guard = httpiface.NewGuard(authorizationSvc, logger)
+	}
 
 	e := httpiface.NewMainRouter(
 		httpiface.NewApplicationsHandler(appSvc, logger),
@@ -97,8 +172,62 @@ func main() {
 		httpiface.NewPermissionsHandler(permSvc, logger),
 		httpiface.NewUsersHandler(userSvc, logger),
 		httpiface.NewAuthorizationHandler(authorizationSvc, logger),
+		httpiface.NewGroupsHandler(groupSvc, logger),
+		httpiface.NewAPIKeysHandler(apiKeySvc, logger),
+		httpiface.NewClaimMappingsHandler(mappingSvc, logger),
 		mw,
 	)
+	if cfg.SweepInterval > 0 {
+		// The sweep has no request segment to attach subsegments to.
+		sweepCtx, err := xray.ContextWithConfig(context.Background(), xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy()})
+		if err != nil {
+			logger.Warn(context.Background(), "failed to configure x-ray for assignment sweep", "error", err)
+		}
+		go sweepExpiredAssignments(sweepCtx, cfg.SweepInterval, dynamodb.NewLeaseRepository(ddbClient), appSvc, userSvc, logger)
+	}
 	logger.Info(context.Background(), "starting http server", "port", cfg.Port)
 	e.Logger.Fatal(e.Start(":" + cfg.Port))
 }
+
+const sweepLease = "assignment-sweep"
+
+// The lease outlives two intervals, so a stopped holder is replaced.
+func sweepExpiredAssignments(ctx context.Context, interval time.Duration, leases ports.LeaseRepository, appSvc *application.ApplicationService, userSvc *application.UserService, logger ports.Logger) {
+	owner := sweepOwner()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		held, err := leases.Acquire(ctx, sweepLease, owner, 2*interval)
+		if err != nil {
+			logger.Error(ctx, "assignment sweep failed to acquire lease", "error", err)
+			continue
+		}
+		if !held {
+			continue
+		}
+		query := domain.ApplicationQuery{Page: domain.PageRequest{Limit: domain.MaxPageLimit}}
+		for {
+			apps, err := appSvc.List(ctx, query)
+			if err != nil {
+				logger.Error(ctx, "assignment sweep failed to list applications", "error", err)
+				break
+			}
+			for _, app := range apps.Items {
+				if _, err := userSvc.PruneExpired(ctx, app.ID); err != nil {
+					logger.Error(ctx, "assignment sweep failed", "app_id", app.ID, "error", err)
+				}
+			}
+			if apps.NextToken == "" {
+				break
+			}
+			query.Page.NextToken = apps.NextToken
+		}
+	}
+}
+
+func sweepOwner() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	return host + "-" + hex.EncodeToString(suffix)
+}
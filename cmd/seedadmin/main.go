@@ -0,0 +1,46 @@
+// Command seedadmin creates the rbac-admin application and makes a user its
+// super-admin.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/application"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/infrastructure/dynamodb"
+)
+
+func main() {
+	userID := flag.String("user", "", "user ID (token sub or API key principal) to make super-admin")
+	flag.Parse()
+
+	tableName, region := os.Getenv("TABLE_NAME"), os.Getenv("AWS_REGION")
+	if tableName == "" || region == "" || *userID == "" {
+		fmt.Fprintln(os.Stderr, "TABLE_NAME, AWS_REGION and -user are required")
+		os.Exit(1)
+	}
+	// There is no request segment to attach DynamoDB subsegments to.
+	xray.Configure(xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy()})
+	ctx := context.Background()
+	client, err := dynamodb.NewClient(ctx, region, tableName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to initialize dynamodb client:", err)
+		os.Exit(1)
+	}
+	svc := application.NewAdminService(
+		dynamodb.NewApplicationRepository(client),
+		dynamodb.NewPermissionRepository(client),
+		dynamodb.NewRoleRepository(client),
+		dynamodb.NewUserRoleRepository(client),
+	)
+	if err := svc.Seed(ctx, *userID); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to seed admin application:", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s is now %s of %s\n", *userID, domain.SuperAdminRole, domain.AdminAppID)
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"rbac-project/internal/domain"
+)
+
+const APIKeyHeader = "x-api-key"
+
+type APIKeyVerifier interface {
+	Verify(ctx context.Context, value string) (domain.APIKey, error)
+}
+
+// /authorize handlers check the application of each check themselves.
+func APIKeyMiddleware(verifier APIKeyVerifier) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			value := c.Request().Header.Get(APIKeyHeader)
+			if value == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing api key"})
+			}
+			key, err := verifier.Verify(c.Request().Context(), value)
+			if errors.Is(err, domain.ErrUnauthorized) {
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid api key"})
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "internal error"})
+			}
+			appID := c.Param("app_id")
+			if appID == "" && strings.HasPrefix(c.Path(), "/applications/:id") {
+				appID = c.Param("id")
+			}
+			switch {
+			case appID != "":
+				if !key.AllowsApp(appID) {
+					return c.JSON(http.StatusForbidden, map[string]string{"error": "api key not allowed for application"})
+				}
+			case strings.HasPrefix(c.Path(), "/authorize"):
+			default:
+				if !key.AllowsAllApps() {
+					return c.JSON(http.StatusForbidden, map[string]string{"error": "api key not allowed for this endpoint"})
+				}
+			}
+			c.Set("user_id", key.Principal)
+			c.Set("api_key", key)
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"rbac-project/internal/domain"
+)
+
+type stubVerifier map[string]domain.APIKey
+
+func (s stubVerifier) Verify(_ context.Context, value string) (domain.APIKey, error) {
+	key, ok := s[value]
+	if !ok {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	verifier := stubVerifier{
+		"docs-key":  {ID: "k1", Principal: "svc-docs", AppIDs: []string{"docs"}},
+		"admin-key": {ID: "k2", Principal: "svc-admin", AppIDs: []string{domain.APIKeyAllApps}},
+	}
+	e := echo.New()
+	g := e.Group("", APIKeyMiddleware(verifier))
+	handler := func(c echo.Context) error {
+		return c.String(http.StatusOK, c.Get("user_id").(string))
+	}
+	g.GET("/applications", handler)
+	g.GET("/applications/:id", handler)
+	g.GET("/applications/:app_id/roles", handler)
+	g.POST("/authorize", handler)
+
+	cases := []struct {
+		name, method, path, key string
+		status                  int
+		body                    string
+	}{
+		{"missing key", http.MethodGet, "/applications/docs/roles", "", http.StatusUnauthorized, ""},
+		{"unknown key", http.MethodGet, "/applications/docs/roles", "nope", http.StatusUnauthorized, ""},
+		{"app in scope", http.MethodGet, "/applications/docs/roles", "docs-key", http.StatusOK, "svc-docs"},
+		{"app id param", http.MethodGet, "/applications/docs", "docs-key", http.StatusOK, "svc-docs"},
+		{"app out of scope", http.MethodGet, "/applications/billing/roles", "docs-key", http.StatusForbidden, ""},
+		{"no app needs all apps", http.MethodGet, "/applications", "docs-key", http.StatusForbidden, ""},
+		{"all apps key", http.MethodGet, "/applications", "admin-key", http.StatusOK, "svc-admin"},
+		{"authorize left to handler", http.MethodPost, "/authorize", "docs-key", http.StatusOK, "svc-docs"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.key != "" {
+				req.Header.Set(APIKeyHeader, tc.key)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.body != "" {
+				assert.Equal(t, tc.body, rec.Body.String())
+			}
+		})
+	}
+}
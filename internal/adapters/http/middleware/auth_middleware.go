@@ -14,12 +14,13 @@ const (
 	ModeNone    Mode = "none"
 	ModeAPIKey  Mode = "api_key"
 	ModeCognito Mode = "cognito"
+	ModeOIDC    Mode = "oidc"
 )
 
 func ParseAuthMode() (Mode, error) {
 	mode := Mode(os.Getenv("AUTH_MODE"))
 	switch mode {
-	case "", ModeNone, ModeAPIKey, ModeCognito:
+	case "", ModeNone, ModeAPIKey, ModeCognito, ModeOIDC:
 		if mode == "" {
 			return ModeNone, nil
 		}
@@ -29,7 +30,7 @@ func ParseAuthMode() (Mode, error) {
 	}
 }
 
-func AuthMiddleware(cognito echo.MiddlewareFunc) (echo.MiddlewareFunc, error) {
+func AuthMiddleware(cognito, apiKey, oidc echo.MiddlewareFunc) (echo.MiddlewareFunc, error) {
 	mode, err := ParseAuthMode()
 	if err != nil {
 		return nil, err
@@ -37,15 +38,23 @@ func AuthMiddleware(cognito echo.MiddlewareFunc) (echo.MiddlewareFunc, error) {
 	if mode == ModeCognito && cognito == nil {
 		return nil, errors.New("cognito middleware is required when AUTH_MODE=cognito")
 	}
+	if mode == ModeAPIKey && apiKey == nil {
+		return nil, errors.New("api key middleware is required when AUTH_MODE=api_key")
+	}
+	if mode == ModeOIDC && oidc == nil {
+		return nil, errors.New("oidc middleware is required when AUTH_MODE=oidc")
+	}
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			switch mode {
 			case ModeNone:
 				return next(c)
 			case ModeAPIKey:
-				return next(c)
+				return apiKey(next)(c)
 			case ModeCognito:
 				return cognito(next)(c)
+			case ModeOIDC:
+				return oidc(next)(c)
 			default:
 				return echo.NewHTTPError(http.StatusInternalServerError, "invalid auth mode")
 			}
@@ -14,7 +14,7 @@ import (
 func TestAuthMiddleware_None(t *testing.T) {
 	t.Setenv("AUTH_MODE", "none")
 
-	mw, err := AuthMiddleware(nil)
+	mw, err := AuthMiddleware(nil, nil, nil)
 	require.NoError(t, err)
 
 	e := echo.New()
@@ -36,7 +36,15 @@ func TestAuthMiddleware_None(t *testing.T) {
 func TestAuthMiddleware_APIKey(t *testing.T) {
 	t.Setenv("AUTH_MODE", "api_key")
 
-	mw, err := AuthMiddleware(nil)
+	apiKeyCalled := false
+	mockAPIKey := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKeyCalled = true
+			return next(c)
+		}
+	}
+
+	mw, err := AuthMiddleware(nil, mockAPIKey, nil)
 	require.NoError(t, err)
 
 	e := echo.New()
@@ -52,9 +60,18 @@ func TestAuthMiddleware_APIKey(t *testing.T) {
 
 	err = h(c)
 	require.NoError(t, err)
+	assert.True(t, apiKeyCalled)
 	assert.True(t, called)
 }
 
+func TestAuthMiddleware_APIKeyRequiresMiddleware(t *testing.T) {
+	t.Setenv("AUTH_MODE", "api_key")
+
+	mw, err := AuthMiddleware(nil, nil, nil)
+	assert.Nil(t, mw)
+	assert.Error(t, err)
+}
+
 func TestAuthMiddleware_Cognito(t *testing.T) {
 	t.Setenv("AUTH_MODE", "cognito")
 
@@ -66,7 +83,7 @@ func TestAuthMiddleware_Cognito(t *testing.T) {
 		}
 	}
 
-	mw, err := AuthMiddleware(mockCognito)
+	mw, err := AuthMiddleware(mockCognito, nil, nil)
 	require.NoError(t, err)
 
 	e := echo.New()
@@ -83,10 +100,46 @@ func TestAuthMiddleware_Cognito(t *testing.T) {
 	assert.True(t, cognitoCalled)
 }
 
+func TestAuthMiddleware_OIDC(t *testing.T) {
+	t.Setenv("AUTH_MODE", "oidc")
+
+	oidcCalled := false
+	mockOIDC := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			oidcCalled = true
+			return next(c)
+		}
+	}
+
+	mw, err := AuthMiddleware(nil, nil, mockOIDC)
+	require.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := mw(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+
+	err = h(c)
+	require.NoError(t, err)
+	assert.True(t, oidcCalled)
+}
+
+func TestAuthMiddleware_OIDCRequiresMiddleware(t *testing.T) {
+	t.Setenv("AUTH_MODE", "oidc")
+
+	mw, err := AuthMiddleware(nil, nil, nil)
+	assert.Nil(t, mw)
+	assert.Error(t, err)
+}
+
 func TestAuthMiddleware_Invalid(t *testing.T) {
 	t.Setenv("AUTH_MODE", "invalid")
 
-	mw, err := AuthMiddleware(nil)
+	mw, err := AuthMiddleware(nil, nil, nil)
 	assert.Nil(t, mw)
 	assert.Error(t, err)
 }
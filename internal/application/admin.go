@@ -0,0 +1,87 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+	"slices"
+	"time"
+)
+
+type AdminService struct {
+	appRepo  ports.ApplicationRepository
+	permRepo ports.PermissionRepository
+	roleRepo ports.RoleRepository
+	userRepo ports.UserRoleRepository
+	logger   ports.Logger
+}
+
+func NewAdminService(appRepo ports.ApplicationRepository, permRepo ports.PermissionRepository, roleRepo ports.RoleRepository, userRepo ports.UserRoleRepository, logger ...ports.Logger) *AdminService {
+	return &AdminService{appRepo: appRepo, permRepo: permRepo, roleRepo: roleRepo, userRepo: userRepo, logger: resolveLogger(logger)}
+}
+
+// Seed is safe to run again, for instance to add another super-admin.
+func (s *AdminService) Seed(ctx context.Context, userID string) error {
+	if userID == "" {
+		s.logger.Warn(ctx, "invalid admin seed input", "user_id", userID)
+		return domain.ErrInvalidInput
+	}
+	now := time.Now().UTC()
+	_, err := s.appRepo.GetByID(ctx, domain.AdminAppID)
+	if errors.Is(err, domain.ErrNotFound) {
+		strict := false
+		err = s.appRepo.Create(ctx, domain.Application{
+			ID:          domain.AdminAppID,
+			Name:        "RBAC administration",
+			Description: "Permissions for managing this service",
+			StrictMode:  &strict,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+	}
+	if err != nil {
+		s.logger.Error(ctx, "failed to ensure admin application", "error", err)
+		return err
+	}
+	permissions, err := s.permRepo.ListByAppID(ctx, domain.AdminAppID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list admin permissions", "error", err)
+		return err
+	}
+	for _, permissionID := range domain.AdminPermissions {
+		if slices.ContainsFunc(permissions, func(p domain.Permission) bool { return p.ID == permissionID }) {
+			continue
+		}
+		err := s.permRepo.Create(ctx, domain.Permission{AppID: domain.AdminAppID, ID: permissionID, Name: permissionID, CreatedAt: now})
+		if err != nil {
+			s.logger.Error(ctx, "failed to create admin permission", "permission_id", permissionID, "error", err)
+			return err
+		}
+	}
+	roles, err := s.roleRepo.ListByAppID(ctx, domain.AdminAppID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list admin roles", "error", err)
+		return err
+	}
+	if _, ok := indexRoles(roles)[domain.SuperAdminRole]; !ok {
+		err := s.roleRepo.Create(ctx, domain.Role{
+			AppID:       domain.AdminAppID,
+			ID:          domain.SuperAdminRole,
+			Name:        "Super admin",
+			Permissions: []string{domain.PermissionWildcard},
+			CreatedAt:   now,
+			UpdatedAt:   now,
+		})
+		if err != nil {
+			s.logger.Error(ctx, "failed to create super-admin role", "error", err)
+			return err
+		}
+	}
+	if err := s.userRepo.AssignRole(ctx, domain.AdminAppID, userID, domain.RoleGrant{RoleID: domain.SuperAdminRole}); err != nil {
+		s.logger.Error(ctx, "failed to assign super-admin role", "user_id", userID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "admin application seeded", "user_id", userID)
+	return nil
+}
@@ -0,0 +1,93 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func TestAdminService_SeedCreatesAdminApp(t *testing.T) {
+	appRepo := new(appRepoMock)
+	permRepo := new(permissionRepoMock)
+	roleRepo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewAdminService(appRepo, permRepo, roleRepo, userRepo)
+	appRepo.On("GetByID", mock.Anything, domain.AdminAppID).Return(domain.Application{}, domain.ErrNotFound)
+	appRepo.On("Create", mock.Anything, mock.MatchedBy(func(app domain.Application) bool {
+		return app.ID == domain.AdminAppID && !app.IsStrict()
+	})).Return(nil)
+	permRepo.On("ListByAppID", mock.Anything, domain.AdminAppID).Return([]domain.Permission{{ID: domain.PermAppsWrite}}, nil)
+	permRepo.On("Create", mock.Anything, mock.MatchedBy(func(p domain.Permission) bool { return p.ID == domain.PermAPIKeysWrite })).Return(nil)
+	permRepo.On("Create", mock.Anything, mock.MatchedBy(func(p domain.Permission) bool { return p.ID == domain.PermUsersRead })).Return(nil)
+	roleRepo.On("ListByAppID", mock.Anything, domain.AdminAppID).Return([]domain.Role{}, nil)
+	roleRepo.On("Create", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
+		return role.ID == domain.SuperAdminRole && assert.ObjectsAreEqual([]string{"*"}, role.Permissions)
+	})).Return(nil)
+	userRepo.On("AssignRole", mock.Anything, domain.AdminAppID, "u1", domain.RoleGrant{RoleID: domain.SuperAdminRole}).Return(nil)
+
+	require.NoError(t, svc.Seed(context.Background(), "u1"))
+	appRepo.AssertExpectations(t)
+	permRepo.AssertExpectations(t)
+	roleRepo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+}
+
+func TestAdminService_SeedIsIdempotent(t *testing.T) {
+	appRepo := new(appRepoMock)
+	permRepo := new(permissionRepoMock)
+	roleRepo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewAdminService(appRepo, permRepo, roleRepo, userRepo)
+	appRepo.On("GetByID", mock.Anything, domain.AdminAppID).Return(domain.Application{ID: domain.AdminAppID}, nil)
+	permRepo.On("ListByAppID", mock.Anything, domain.AdminAppID).Return([]domain.Permission{{ID: domain.PermAppsWrite}, {ID: domain.PermAPIKeysWrite}, {ID: domain.PermUsersRead}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, domain.AdminAppID).Return([]domain.Role{{ID: domain.SuperAdminRole}}, nil)
+	userRepo.On("AssignRole", mock.Anything, domain.AdminAppID, "u2", domain.RoleGrant{RoleID: domain.SuperAdminRole}).Return(nil)
+
+	require.NoError(t, svc.Seed(context.Background(), "u2"))
+	appRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	permRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	roleRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	assert.ErrorIs(t, svc.Seed(context.Background(), ""), domain.ErrInvalidInput)
+}
+
+func TestApplicationService_AdminAppIsReserved(t *testing.T) {
+	repo := new(appRepoMock)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
+
+	err := svc.Create(context.Background(), domain.Application{ID: domain.AdminAppID, Name: "mine"})
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	err = svc.Update(context.Background(), domain.Application{ID: domain.AdminAppID, Name: "mine"})
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	_, err = svc.Delete(context.Background(), domain.AdminAppID, true, "")
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestAuthorizationService_ManagementPermissions(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("GetByUserAndApp", mock.Anything, domain.AdminAppID, "u1").
+		Return(domain.UserAppRoles{AppID: domain.AdminAppID, UserID: "u1", Roles: []string{"billing-admin"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, domain.AdminAppID).Return([]domain.Role{
+		{ID: "billing-admin", Permissions: []string{"roles:write:billing", "assignments:write:*"}},
+	}, nil)
+
+	for permission, want := range map[string]bool{
+		domain.RolesWritePermission("billing"):         true,
+		domain.RolesWritePermission("docs"):            false,
+		domain.AssignmentsWritePermission("docs"):      true,
+		domain.GroupsWritePermission("billing"):        false,
+		domain.PermAppsWrite:                           false,
+		domain.RolesWritePermission(domain.AdminAppID): false,
+	} {
+		allowed, err := svc.IsAllowed(context.Background(), domain.AdminAppID, "u1", permission)
+		require.NoError(t, err)
+		assert.Equal(t, want, allowed, permission)
+	}
+}
@@ -0,0 +1,133 @@
+package application
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Issued keys read rbac_<key_id>_<secret>.
+const apiKeyPrefix = "rbac_"
+
+// Keys carry 256 random bits, so an unsalted SHA-256 is enough to store them.
+type APIKeyService struct {
+	repo   ports.APIKeyRepository
+	logger ports.Logger
+}
+
+func NewAPIKeyService(repo ports.APIKeyRepository, logger ...ports.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, logger: resolveLogger(logger)}
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *APIKeyService) Create(ctx context.Context, key domain.APIKey) (domain.IssuedAPIKey, error) {
+	now := time.Now().UTC()
+	key.AppIDs = slices.Compact(slices.Sorted(slices.Values(key.AppIDs)))
+	if key.Name == "" || len(key.AppIDs) == 0 || slices.Contains(key.AppIDs, "") || !key.ExpiresAt.After(now) {
+		s.logger.Warn(ctx, "invalid api key create input", "name", key.Name, "app_ids", key.AppIDs)
+		return domain.IssuedAPIKey{}, domain.ErrInvalidInput
+	}
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	key.ID = hex.EncodeToString(id)
+	// Callers may not pick the principal: it would let them issue a key for
+	// someone holding more admin permissions than they do.
+	key.Principal = "apikey:" + key.ID
+	value := apiKeyPrefix + key.ID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	key.Hash = hashAPIKey(value)
+	key.Revoked = false
+	key.RevokedAt = nil
+	key.CreatedAt = now
+	key.ExpiresAt = key.ExpiresAt.UTC().Truncate(time.Second)
+	if err := s.repo.Create(ctx, key); err != nil {
+		s.logger.Error(ctx, "failed to create api key", "key_id", key.ID, "error", err)
+		return domain.IssuedAPIKey{}, err
+	}
+	s.logger.Info(ctx, "api key created", "key_id", key.ID, "principal", key.Principal, "app_ids", key.AppIDs)
+	return domain.IssuedAPIKey{APIKey: key, Key: value}, nil
+}
+
+func (s *APIKeyService) Get(ctx context.Context, keyID string) (domain.APIKey, error) {
+	if keyID == "" {
+		s.logger.Warn(ctx, "invalid api key get input", "key_id", keyID)
+		return domain.APIKey{}, domain.ErrInvalidInput
+	}
+	key, err := s.repo.Get(ctx, keyID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get api key", "key_id", keyID, "error", err)
+		return domain.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, page domain.PageRequest) (domain.Page[domain.APIKey], error) {
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid api keys page", "limit", page.Limit)
+		return domain.Page[domain.APIKey]{}, err
+	}
+	keys, err := s.repo.List(ctx, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list api keys", "error", err)
+		return domain.Page[domain.APIKey]{}, err
+	}
+	s.logger.Debug(ctx, "api keys listed", "count", len(keys.Items))
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, keyID string) error {
+	if keyID == "" {
+		s.logger.Warn(ctx, "invalid api key revoke input", "key_id", keyID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.repo.Revoke(ctx, keyID, time.Now().UTC()); err != nil {
+		s.logger.Error(ctx, "failed to revoke api key", "key_id", keyID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "api key revoked", "key_id", keyID)
+	return nil
+}
+
+func (s *APIKeyService) Verify(ctx context.Context, value string) (domain.APIKey, error) {
+	keyID, _, ok := strings.Cut(strings.TrimPrefix(value, apiKeyPrefix), "_")
+	if !strings.HasPrefix(value, apiKeyPrefix) || !ok || keyID == "" {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	key, err := s.repo.Get(ctx, keyID)
+	if errors.Is(err, domain.ErrNotFound) {
+		s.logger.Warn(ctx, "unknown api key", "key_id", keyID)
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	if err != nil {
+		s.logger.Error(ctx, "failed to get api key for verification", "key_id", keyID, "error", err)
+		return domain.APIKey{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(value)), []byte(key.Hash)) != 1 {
+		s.logger.Warn(ctx, "api key secret mismatch", "key_id", keyID)
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	if !key.Usable(time.Now()) {
+		s.logger.Warn(ctx, "api key revoked or expired", "key_id", keyID, "revoked", key.Revoked, "expires_at", key.ExpiresAt)
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	return key, nil
+}
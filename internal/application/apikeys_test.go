@@ -0,0 +1,108 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func issueTestKey(t *testing.T, repo *apiKeyRepoMock, svc *APIKeyService) domain.IssuedAPIKey {
+	t.Helper()
+	var stored domain.APIKey
+	repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(domain.APIKey)
+	}).Return(nil).Once()
+	issued, err := svc.Create(context.Background(), domain.APIKey{Name: "ci", Principal: "super-admin-user", AppIDs: []string{"docs", "billing", "docs"}, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	repo.On("Get", mock.Anything, issued.ID).Return(stored, nil)
+	return issued
+}
+
+func TestAPIKeyService_CreateAndVerify(t *testing.T) {
+	repo := new(apiKeyRepoMock)
+	svc := NewAPIKeyService(repo)
+	issued := issueTestKey(t, repo, svc)
+
+	assert.True(t, strings.HasPrefix(issued.Key, "rbac_"+issued.ID+"_"))
+	assert.NotContains(t, issued.Hash, issued.Key)
+	assert.Equal(t, "apikey:"+issued.ID, issued.Principal)
+	assert.Equal(t, []string{"billing", "docs"}, issued.AppIDs)
+
+	key, err := svc.Verify(context.Background(), issued.Key)
+	require.NoError(t, err)
+	assert.Equal(t, issued.ID, key.ID)
+
+	_, err = svc.Verify(context.Background(), issued.Key+"x")
+	assert.ErrorIs(t, err, domain.ErrUnauthorized)
+}
+
+func TestAPIKeyService_VerifyRejects(t *testing.T) {
+	repo := new(apiKeyRepoMock)
+	svc := NewAPIKeyService(repo)
+	repo.On("Get", mock.Anything, "missing").Return(domain.APIKey{}, domain.ErrNotFound)
+	repo.On("Get", mock.Anything, "revoked").Return(domain.APIKey{ID: "revoked", Hash: hashAPIKey("rbac_revoked_s"), Revoked: true, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	repo.On("Get", mock.Anything, "expired").Return(domain.APIKey{ID: "expired", Hash: hashAPIKey("rbac_expired_s"), ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+
+	for _, value := range []string{"", "rbac_", "rbac_nosecret", "other_missing_s", "rbac_missing_s", "rbac_revoked_s", "rbac_expired_s"} {
+		_, err := svc.Verify(context.Background(), value)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized, value)
+	}
+}
+
+func TestAPIKeyService_CreateInvalidInput(t *testing.T) {
+	svc := NewAPIKeyService(new(apiKeyRepoMock))
+	future := time.Now().Add(time.Hour)
+
+	for _, key := range []domain.APIKey{
+		{AppIDs: []string{"docs"}, ExpiresAt: future},
+		{Name: "ci", ExpiresAt: future},
+		{Name: "ci", AppIDs: []string{""}, ExpiresAt: future},
+		{Name: "ci", AppIDs: []string{"docs"}},
+		{Name: "ci", AppIDs: []string{"docs"}, ExpiresAt: time.Now().Add(-time.Hour)},
+	} {
+		_, err := svc.Create(context.Background(), key)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	}
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	repo := new(apiKeyRepoMock)
+	svc := NewAPIKeyService(repo)
+	repo.On("Revoke", mock.Anything, "k1", mock.Anything).Return(nil)
+	repo.On("Revoke", mock.Anything, "gone", mock.Anything).Return(domain.ErrNotFound)
+
+	require.NoError(t, svc.Revoke(context.Background(), "k1"))
+	assert.ErrorIs(t, svc.Revoke(context.Background(), "gone"), domain.ErrNotFound)
+	assert.ErrorIs(t, svc.Revoke(context.Background(), ""), domain.ErrInvalidInput)
+}
+
+func TestAPIKeyService_GetAndList(t *testing.T) {
+	repo := new(apiKeyRepoMock)
+	svc := NewAPIKeyService(repo)
+	key := domain.APIKey{ID: "k1", Name: "ci", AppIDs: []string{"docs"}}
+	repo.On("Get", mock.Anything, "k1").Return(key, nil).Once()
+	repo.On("Get", mock.Anything, "gone").Return(domain.APIKey{}, domain.ErrNotFound).Once()
+	repo.On("List", mock.Anything, domain.PageRequest{Limit: domain.DefaultPageLimit}).
+		Return(domain.Page[domain.APIKey]{Items: []domain.APIKey{key}}, nil).Once()
+
+	got, err := svc.Get(context.Background(), "k1")
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+	_, err = svc.Get(context.Background(), "gone")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = svc.Get(context.Background(), "")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	keys, err := svc.List(context.Background(), domain.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, keys.Items, 1)
+	_, err = svc.List(context.Background(), domain.PageRequest{Limit: domain.MaxPageLimit + 1})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+}
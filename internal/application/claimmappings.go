@@ -0,0 +1,160 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+	"time"
+)
+
+type ClaimMappingService struct {
+	repo     ports.ClaimMappingRepository
+	roleRepo ports.RoleRepository
+	userRepo ports.UserRoleRepository
+	logger   ports.Logger
+}
+
+func NewClaimMappingService(repo ports.ClaimMappingRepository, roleRepo ports.RoleRepository, userRepo ports.UserRoleRepository, logger ...ports.Logger) *ClaimMappingService {
+	return &ClaimMappingService{repo: repo, roleRepo: roleRepo, userRepo: userRepo, logger: resolveLogger(logger)}
+}
+
+func (s *ClaimMappingService) checkMapping(ctx context.Context, mapping domain.ClaimMapping) error {
+	if mapping.AppID == "" || mapping.ID == "" || mapping.Claim == "" || mapping.Value == "" || mapping.RoleID == "" {
+		s.logger.Warn(ctx, "invalid claim mapping input", "app_id", mapping.AppID, "mapping_id", mapping.ID)
+		return domain.ErrInvalidInput
+	}
+	if err := domain.ValidateID(mapping.ID); err != nil {
+		s.logger.Warn(ctx, "invalid claim mapping id", "app_id", mapping.AppID, "mapping_id", mapping.ID)
+		return err
+	}
+	roles, err := s.roleRepo.ListByAppID(ctx, mapping.AppID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for claim mapping", "app_id", mapping.AppID, "mapping_id", mapping.ID, "error", err)
+		return err
+	}
+	if _, ok := indexRoles(roles)[mapping.RoleID]; !ok {
+		s.logger.Warn(ctx, "claim mapping references unknown role", "app_id", mapping.AppID, "mapping_id", mapping.ID, "role_id", mapping.RoleID)
+		return fmt.Errorf("%w: unknown role: %s", domain.ErrUnprocessable, mapping.RoleID)
+	}
+	return nil
+}
+
+func (s *ClaimMappingService) Create(ctx context.Context, mapping domain.ClaimMapping) error {
+	if err := s.checkMapping(ctx, mapping); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	mapping.CreatedAt = now
+	mapping.UpdatedAt = now
+	if err := s.repo.Create(ctx, mapping); err != nil {
+		s.logger.Error(ctx, "failed to create claim mapping", "app_id", mapping.AppID, "mapping_id", mapping.ID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "claim mapping created", "app_id", mapping.AppID, "mapping_id", mapping.ID, "claim", mapping.Claim, "role_id", mapping.RoleID)
+	return nil
+}
+
+func (s *ClaimMappingService) Update(ctx context.Context, mapping domain.ClaimMapping) error {
+	if err := s.checkMapping(ctx, mapping); err != nil {
+		return err
+	}
+	previous, err := s.repo.Get(ctx, mapping.AppID, mapping.ID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get claim mapping for update", "app_id", mapping.AppID, "mapping_id", mapping.ID, "error", err)
+		return err
+	}
+	mapping.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, mapping); err != nil {
+		s.logger.Error(ctx, "failed to update claim mapping", "app_id", mapping.AppID, "mapping_id", mapping.ID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "claim mapping updated", "app_id", mapping.AppID, "mapping_id", mapping.ID, "claim", mapping.Claim, "role_id", mapping.RoleID)
+	if previous.RoleID != mapping.RoleID || !mapping.Sync {
+		s.revokeSynced(ctx, previous)
+	}
+	return nil
+}
+
+func (s *ClaimMappingService) Get(ctx context.Context, appID, mappingID string) (domain.ClaimMapping, error) {
+	if appID == "" || mappingID == "" {
+		s.logger.Warn(ctx, "invalid claim mapping get input", "app_id", appID, "mapping_id", mappingID)
+		return domain.ClaimMapping{}, domain.ErrInvalidInput
+	}
+	mapping, err := s.repo.Get(ctx, appID, mappingID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get claim mapping", "app_id", appID, "mapping_id", mappingID, "error", err)
+		return domain.ClaimMapping{}, err
+	}
+	return mapping, nil
+}
+
+func (s *ClaimMappingService) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.ClaimMapping], error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid claim mappings query", "app_id", appID)
+		return domain.Page[domain.ClaimMapping]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid claim mappings page", "app_id", appID, "limit", page.Limit)
+		return domain.Page[domain.ClaimMapping]{}, err
+	}
+	mappings, err := s.repo.ListPageByAppID(ctx, appID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list claim mappings", "app_id", appID, "error", err)
+		return domain.Page[domain.ClaimMapping]{}, err
+	}
+	s.logger.Debug(ctx, "claim mappings listed", "app_id", appID, "count", len(mappings.Items))
+	return mappings, nil
+}
+
+// Delete also revokes the grants the mapping synced.
+func (s *ClaimMappingService) Delete(ctx context.Context, appID, mappingID string) error {
+	if appID == "" || mappingID == "" {
+		s.logger.Warn(ctx, "invalid claim mapping delete input", "app_id", appID, "mapping_id", mappingID)
+		return domain.ErrInvalidInput
+	}
+	mapping, err := s.repo.Get(ctx, appID, mappingID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get claim mapping for delete", "app_id", appID, "mapping_id", mappingID, "error", err)
+		return err
+	}
+	if err := s.repo.Delete(ctx, appID, mappingID); err != nil {
+		s.logger.Error(ctx, "failed to delete claim mapping", "app_id", appID, "mapping_id", mappingID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "claim mapping deleted", "app_id", appID, "mapping_id", mappingID)
+	s.revokeSynced(ctx, mapping)
+	return nil
+}
+
+// revokeSynced runs after the mapping is written, so failures are only
+// logged: authorization drops grants without a syncing mapping anyway.
+func (s *ClaimMappingService) revokeSynced(ctx context.Context, mapping domain.ClaimMapping) {
+	source := domain.ClaimMappingSource(mapping.ID)
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		members, err := s.userRepo.ListByRole(ctx, mapping.AppID, mapping.RoleID, page)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list synced role members", "app_id", mapping.AppID, "mapping_id", mapping.ID, "role_id", mapping.RoleID, "error", err)
+			return
+		}
+		for _, member := range members.Items {
+			if member.Source != source {
+				continue
+			}
+			grant := domain.RoleGrant{RoleID: member.RoleID, Scope: member.Scope, Source: source}
+			err := s.userRepo.RevokeSynced(ctx, mapping.AppID, member.UserID, grant)
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				s.logger.Error(ctx, "failed to revoke synced role", "app_id", mapping.AppID, "mapping_id", mapping.ID, "user_id", member.UserID, "error", err)
+				continue
+			}
+			s.logger.Info(ctx, "synced role revoked", "app_id", mapping.AppID, "mapping_id", mapping.ID, "user_id", member.UserID, "role_id", member.RoleID)
+		}
+		if members.NextToken == "" {
+			return
+		}
+		page.NextToken = members.NextToken
+	}
+}
@@ -0,0 +1,279 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func TestClaimMappingService_CreateValidates(t *testing.T) {
+	repo := new(claimMappingRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewClaimMappingService(repo, roleRepo, new(userRoleRepoMock))
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.Create(context.Background(), domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "cognito:groups", RoleID: "editor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = svc.Create(context.Background(), domain.ClaimMapping{AppID: "a1", ID: "m#1", Claim: "cognito:groups", Value: "writers", RoleID: "editor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = svc.Create(context.Background(), domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "cognito:groups", Value: "writers", RoleID: "ghost"})
+	require.ErrorIs(t, err, domain.ErrUnprocessable)
+	assert.Contains(t, err.Error(), "ghost")
+
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(m domain.ClaimMapping) bool {
+		return m.ID == "m1" && m.RoleID == "editor" && m.Sync && !m.CreatedAt.IsZero() && m.CreatedAt.Equal(m.UpdatedAt)
+	})).Return(nil).Once()
+	err = svc.Create(context.Background(), domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "cognito:groups", Value: "writers", RoleID: "editor", Sync: true})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestClaimMappingService_UpdateAndDelete(t *testing.T) {
+	repo := new(claimMappingRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewClaimMappingService(repo, roleRepo, new(userRoleRepoMock))
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+	repo.On("Get", mock.Anything, "a1", "m1").Return(domain.ClaimMapping{}, domain.ErrNotFound).Twice()
+
+	err := svc.Update(context.Background(), domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "cognito:groups", Value: "admins", RoleID: "admin"})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.ErrorIs(t, svc.Delete(context.Background(), "a1", "m1"), domain.ErrNotFound)
+	assert.ErrorIs(t, svc.Delete(context.Background(), "a1", ""), domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func syncedMembers(roleID string) domain.Page[domain.RoleMember] {
+	return domain.Page[domain.RoleMember]{Items: []domain.RoleMember{
+		{AppID: "a1", RoleID: roleID, UserID: "u1", Source: domain.ClaimMappingSource("m1")},
+		{AppID: "a1", RoleID: roleID, UserID: "u2"},
+		{AppID: "a1", RoleID: roleID, UserID: "u3", Source: domain.ClaimMappingSource("m2")},
+	}}
+}
+
+func TestClaimMappingService_UpdateRevokesGrantsSyncedForOldRole(t *testing.T) {
+	repo := new(claimMappingRepoMock)
+	roleRepo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewClaimMappingService(repo, roleRepo, userRepo)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+	previous := domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "cognito:groups", Value: "writers", RoleID: "editor", Sync: true}
+	repo.On("Get", mock.Anything, "a1", "m1").Return(previous, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(m domain.ClaimMapping) bool {
+		return m.ID == "m1" && !m.UpdatedAt.IsZero()
+	})).Return(nil).Twice()
+	userRepo.On("ListByRole", mock.Anything, "a1", "editor", domain.PageRequest{Limit: domain.MaxPageLimit}).Return(syncedMembers("editor"), nil).Once()
+	userRepo.On("RevokeSynced", mock.Anything, "a1", "u1", domain.RoleGrant{RoleID: "editor", Source: domain.ClaimMappingSource("m1")}).Return(nil).Once()
+
+	updated := previous
+	updated.Value = "authors"
+	require.NoError(t, svc.Update(context.Background(), updated))
+	userRepo.AssertNotCalled(t, "ListByRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	updated.RoleID = "admin"
+	require.NoError(t, svc.Update(context.Background(), updated))
+	repo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+}
+
+func TestClaimMappingService_DeleteRevokesSyncedGrants(t *testing.T) {
+	repo := new(claimMappingRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewClaimMappingService(repo, new(roleRepoMock), userRepo)
+	repo.On("Get", mock.Anything, "a1", "m1").Return(domain.ClaimMapping{AppID: "a1", ID: "m1", RoleID: "editor", Sync: true}, nil).Once()
+	repo.On("Delete", mock.Anything, "a1", "m1").Return(nil).Once()
+	first := syncedMembers("editor")
+	first.NextToken = "next"
+	userRepo.On("ListByRole", mock.Anything, "a1", "editor", domain.PageRequest{Limit: domain.MaxPageLimit}).Return(first, nil).Once()
+	userRepo.On("ListByRole", mock.Anything, "a1", "editor", domain.PageRequest{Limit: domain.MaxPageLimit, NextToken: "next"}).
+		Return(domain.Page[domain.RoleMember]{Items: []domain.RoleMember{{AppID: "a1", RoleID: "editor", UserID: "u4", Scope: "org/acme", Source: domain.ClaimMappingSource("m1")}}}, nil).Once()
+	userRepo.On("RevokeSynced", mock.Anything, "a1", "u1", domain.RoleGrant{RoleID: "editor", Source: domain.ClaimMappingSource("m1")}).Return(errors.New("throttled")).Once()
+	userRepo.On("RevokeSynced", mock.Anything, "a1", "u4", domain.RoleGrant{RoleID: "editor", Scope: "org/acme", Source: domain.ClaimMappingSource("m1")}).Return(domain.ErrNotFound).Once()
+
+	require.NoError(t, svc.Delete(context.Background(), "a1", "m1"))
+	repo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+}
+
+func TestClaimMappingService_GetAndList(t *testing.T) {
+	repo := new(claimMappingRepoMock)
+	svc := NewClaimMappingService(repo, new(roleRepoMock), new(userRoleRepoMock))
+	mapping := domain.ClaimMapping{AppID: "a1", ID: "m1", Claim: "groups", Value: "writers", RoleID: "editor"}
+	repo.On("Get", mock.Anything, "a1", "m1").Return(mapping, nil).Once()
+	repo.On("ListPageByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.DefaultPageLimit}).
+		Return(domain.Page[domain.ClaimMapping]{Items: []domain.ClaimMapping{mapping}}, nil).Once()
+
+	got, err := svc.Get(context.Background(), "a1", "m1")
+	require.NoError(t, err)
+	assert.Equal(t, mapping, got)
+	_, err = svc.Get(context.Background(), "", "m1")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	page, err := svc.ListByAppID(context.Background(), "a1", domain.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	_, err = svc.ListByAppID(context.Background(), "a1", domain.PageRequest{Limit: domain.MaxPageLimit + 1})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+}
+
+func writersMapping(sync bool) []domain.ClaimMapping {
+	return []domain.ClaimMapping{
+		{AppID: "a1", ID: "writers", Claim: "cognito:groups", Value: "writers", RoleID: "editor", Sync: sync},
+		{AppID: "a1", ID: "admins", Claim: "cognito:groups", Value: "admins", RoleID: "admin", Sync: sync},
+	}
+}
+
+var writerClaims = map[string]any{"sub": "u1", "cognito:groups": []any{"writers"}}
+
+func TestAuthorizationService_MappedRolesWithoutAssignment(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound).Once()
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(false), nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil).Once()
+
+	decisions, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: writerClaims},
+		{AppID: "a1", UserID: "u1", Permission: "doc:delete", Claims: writerClaims},
+	})
+	require.NoError(t, err)
+	assert.True(t, decisions[0].Allowed)
+	assert.False(t, decisions[1].Allowed)
+	userRepo.AssertExpectations(t)
+	mappingRepo.AssertExpectations(t)
+	roleRepo.AssertExpectations(t)
+}
+
+func TestAuthorizationService_WithoutClaimsSkipsMappings(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, new(roleRepoMock), new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound)
+
+	decision, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:write"})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	mappingRepo.AssertNotCalled(t, "ListByAppID", mock.Anything, mock.Anything)
+}
+
+func TestAuthorizationService_SyncsMappedRolesOnce(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound).Once()
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(true), nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil).Once()
+	synced := domain.RoleGrant{RoleID: "editor", Source: domain.ClaimMappingSource("writers")}
+	userRepo.On("AssignRole", mock.Anything, "a1", "u1", synced).Return(errors.New("throttled")).Once()
+
+	decisions, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: writerClaims},
+		{AppID: "a1", UserID: "u1", Permission: "doc:read", Claims: writerClaims},
+	})
+	require.NoError(t, err)
+	assert.True(t, decisions[0].Allowed)
+	assert.True(t, decisions[1].Allowed)
+	userRepo.AssertExpectations(t)
+}
+
+func TestAuthorizationService_SyncSkipsHeldAndUnknownRoles(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").
+		Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"editor"}}, nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.ClaimMapping{
+		{AppID: "a1", ID: "writers", Claim: "cognito:groups", Value: "writers", RoleID: "editor", Sync: true},
+		{AppID: "a1", ID: "legacy", Claim: "cognito:groups", Value: "writers", RoleID: "ghost", Sync: true},
+	}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	decision, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: writerClaims})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthorizationService_ExplainMappedRoles(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(true), nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	got, err := svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: writerClaims})
+	require.NoError(t, err)
+	assert.True(t, got.Allowed)
+	assert.Equal(t, domain.ReasonGranted, got.Reason)
+	assert.Equal(t, []string{"editor"}, got.MappedRoles)
+	assert.Equal(t, []string{"editor"}, got.ActiveRoles)
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	got, err = svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: map[string]any{"cognito:groups": []any{"readers"}}})
+	require.NoError(t, err)
+	assert.Equal(t, domain.ReasonNoAssignment, got.Reason)
+}
+
+func TestAuthorizationService_RevokesStaleSyncedGrants(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	fromWriters := domain.RoleGrant{RoleID: "editor", Source: domain.ClaimMappingSource("writers")}
+	fromDeleted := domain.RoleGrant{RoleID: "admin", Source: domain.ClaimMappingSource("gone")}
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		AppID: "a1", UserID: "u1", Roles: []string{"editor", "admin", "viewer"},
+		Grants: []domain.RoleGrant{fromWriters, fromDeleted, {RoleID: "viewer"}},
+	}, nil).Once()
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(true), nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil).Once()
+	userRepo.On("RevokeSynced", mock.Anything, "a1", "u1", fromWriters).Return(nil).Once()
+	userRepo.On("RevokeSynced", mock.Anything, "a1", "u1", fromDeleted).Return(errors.New("throttled")).Once()
+
+	readerClaims := map[string]any{"cognito:groups": []any{"readers"}}
+	decisions, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:write", Claims: readerClaims},
+		{AppID: "a1", UserID: "u1", Permission: "doc:read", Claims: readerClaims},
+	})
+	require.NoError(t, err)
+	assert.False(t, decisions[0].Allowed)
+	assert.True(t, decisions[1].Allowed)
+	userRepo.AssertExpectations(t)
+}
+
+func TestAuthorizationService_StaleSyncedGrantsWithoutClaims(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		AppID: "a1", UserID: "u1", Roles: []string{"editor", "admin"},
+		Grants: []domain.RoleGrant{
+			{RoleID: "editor", Source: domain.ClaimMappingSource("writers")},
+			{RoleID: "admin", Source: domain.ClaimMappingSource("gone")},
+		},
+	}, nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(true), nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	got, err := svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:delete"})
+	require.NoError(t, err)
+	assert.False(t, got.Allowed)
+	assert.Equal(t, []string{"editor"}, got.ActiveRoles)
+	userRepo.AssertNotCalled(t, "RevokeSynced", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,536 @@
+package application
+
+import (
+	"fmt"
+	"rbac-project/internal/domain"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// The condition grammar is described in the README. A condition that fails to
+// evaluate does not hold.
+const (
+	maxConditionLength = 512
+	maxConditionNodes  = 64
+	maxConditionDepth  = 16
+)
+
+type condition struct {
+	root conditionNode
+}
+
+func (c *condition) holds(attrs map[string]any) bool {
+	value, err := c.root.eval(attrs)
+	if err != nil {
+		return false
+	}
+	result, ok := value.(bool)
+	return ok && result
+}
+
+// kindAny is for attribute paths, whose type is only known at evaluation.
+type valueKind int
+
+const (
+	kindAny valueKind = iota
+	kindBool
+	kindNumber
+	kindString
+	kindList
+)
+
+type conditionNode interface {
+	eval(attrs map[string]any) (any, error)
+	kind() valueKind
+}
+
+type literalNode struct{ value any }
+
+func (n literalNode) eval(map[string]any) (any, error) { return n.value, nil }
+
+func (n literalNode) kind() valueKind {
+	switch n.value.(type) {
+	case bool:
+		return kindBool
+	case float64:
+		return kindNumber
+	case string:
+		return kindString
+	default:
+		return kindList
+	}
+}
+
+type pathNode struct{ path []string }
+
+func (n pathNode) eval(attrs map[string]any) (any, error) {
+	var current any = attrs
+	for _, key := range n.path {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an object", strings.Join(n.path, "."))
+		}
+		if current, ok = object[key]; !ok {
+			return nil, fmt.Errorf("%s is not set", strings.Join(n.path, "."))
+		}
+	}
+	return normalizeValue(current), nil
+}
+
+func (pathNode) kind() valueKind { return kindAny }
+
+type notNode struct{ operand conditionNode }
+
+func (n notNode) eval(attrs map[string]any) (any, error) {
+	value, err := evalBool(n.operand, attrs)
+	return !value, err
+}
+
+func (notNode) kind() valueKind { return kindBool }
+
+type logicalNode struct {
+	and         bool
+	left, right conditionNode
+}
+
+func (n logicalNode) eval(attrs map[string]any) (any, error) {
+	left, err := evalBool(n.left, attrs)
+	if err != nil {
+		return nil, err
+	}
+	if left != n.and {
+		return left, nil
+	}
+	return evalBool(n.right, attrs)
+}
+
+func (logicalNode) kind() valueKind { return kindBool }
+
+type compareNode struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n compareNode) eval(attrs map[string]any) (any, error) {
+	left, err := n.left.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(attrs)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equalValues(left, right), nil
+	case "!=":
+		return !equalValues(left, right), nil
+	case "in":
+		list, ok := right.([]any)
+		if !ok {
+			return nil, fmt.Errorf("right side of in is not a list")
+		}
+		return slices.ContainsFunc(list, func(item any) bool { return equalValues(left, item) }), nil
+	}
+	var order int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare number with %T", right)
+		}
+		order = compareOrdered(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot compare string with %T", right)
+		}
+		order = strings.Compare(l, r)
+	default:
+		return nil, fmt.Errorf("cannot order %T", left)
+	}
+	switch n.op {
+	case "<":
+		return order < 0, nil
+	case "<=":
+		return order <= 0, nil
+	case ">":
+		return order > 0, nil
+	default:
+		return order >= 0, nil
+	}
+}
+
+func (compareNode) kind() valueKind { return kindBool }
+
+func evalBool(node conditionNode, attrs map[string]any) (bool, error) {
+	value, err := node.eval(attrs)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%T is not a boolean", value)
+	}
+	return result, nil
+}
+
+func compareOrdered(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func equalValues(a, b any) bool {
+	switch a.(type) {
+	case bool, float64, string:
+		return a == b
+	default:
+		return false
+	}
+}
+
+func normalizeValue(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case []string:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	case []any:
+		list := make([]any, len(v))
+		for i, item := range v {
+			list[i] = normalizeValue(item)
+		}
+		return list
+	default:
+		return value
+	}
+}
+
+func compileCondition(expr string) (*condition, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("%w: empty condition", domain.ErrInvalidInput)
+	}
+	if len(expr) > maxConditionLength {
+		return nil, fmt.Errorf("%w: condition longer than %d characters", domain.ErrInvalidInput, maxConditionLength)
+	}
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: condition %q: %v", domain.ErrInvalidInput, expr, err)
+	}
+	p := &conditionParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	if err == nil && root.kind() != kindBool && root.kind() != kindAny {
+		err = fmt.Errorf("expression is not boolean")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: condition %q: %v", domain.ErrInvalidInput, expr, err)
+	}
+	return &condition{root: root}, nil
+}
+
+type tokenType int
+
+const (
+	tokenNumber tokenType = iota
+	tokenString
+	tokenIdent
+	tokenOperator
+)
+
+type conditionToken struct {
+	typ   tokenType
+	text  string
+	value any
+}
+
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			text := expr[i+1 : i+1+end]
+			tokens = append(tokens, conditionToken{typ: tokenString, text: text, value: text})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '-' && i+1 < len(expr) && expr[i+1] >= '0' && expr[i+1] <= '9':
+			j := i + 1
+			for j < len(expr) && (expr[j] >= '0' && expr[j] <= '9' || expr[j] == '.') {
+				j++
+			}
+			number, err := strconv.ParseFloat(expr[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q", expr[i:j])
+			}
+			tokens = append(tokens, conditionToken{typ: tokenNumber, text: expr[i:j], value: number})
+			i = j
+		case isIdentStart(c):
+			j := i + 1
+			for j < len(expr) && (isIdentStart(expr[j]) || expr[j] == '.' || expr[j] >= '0' && expr[j] <= '9') {
+				j++
+			}
+			tokens = append(tokens, conditionToken{typ: tokenIdent, text: expr[i:j]})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(expr[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				r, _ := utf8.DecodeRuneInString(expr[i:])
+				return nil, fmt.Errorf("unexpected character %q", r)
+			}
+			tokens = append(tokens, conditionToken{typ: tokenOperator, text: op})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// Identifiers are ASCII only.
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+	nodes  int
+	depth  int
+}
+
+func (p *conditionParser) peek(text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].typ != tokenString && p.tokens[p.pos].text == text
+}
+
+func (p *conditionParser) node(n conditionNode) (conditionNode, error) {
+	p.nodes++
+	if p.nodes > maxConditionNodes {
+		return nil, fmt.Errorf("more than %d terms", maxConditionNodes)
+	}
+	return n, nil
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxConditionDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxConditionDepth)
+	}
+	left, err := p.parseAnd()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right conditionNode
+		if right, err = p.parseAnd(); err == nil {
+			left, err = p.logical(false, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right conditionNode
+		if right, err = p.parseNot(); err == nil {
+			left, err = p.logical(true, left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *conditionParser) logical(and bool, left, right conditionNode) (conditionNode, error) {
+	if !isBoolish(left) || !isBoolish(right) {
+		return nil, fmt.Errorf("operands of && and || must be boolean")
+	}
+	return p.node(logicalNode{and: and, left: left, right: right})
+}
+
+func (p *conditionParser) parseNot() (conditionNode, error) {
+	if !p.peek("!") {
+		return p.parseComparison()
+	}
+	p.pos++
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxConditionDepth {
+		return nil, fmt.Errorf("nested deeper than %d levels", maxConditionDepth)
+	}
+	operand, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	if !isBoolish(operand) {
+		return nil, fmt.Errorf("operand of ! must be boolean")
+	}
+	return p.node(notNode{operand: operand})
+}
+
+func (p *conditionParser) parseComparison() (conditionNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">", "in"} {
+		if !p.peek(op) {
+			continue
+		}
+		p.pos++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkComparison(op, left.kind(), right.kind()); err != nil {
+			return nil, err
+		}
+		return p.node(compareNode{op: op, left: left, right: right})
+	}
+	return left, nil
+}
+
+func checkComparison(op string, left, right valueKind) error {
+	switch {
+	case op == "in":
+		if left == kindList || right != kindList && right != kindAny {
+			return fmt.Errorf("in needs a value on the left and a list on the right")
+		}
+	case left == kindList || right == kindList:
+		return fmt.Errorf("lists can only appear on the right of in")
+	case op == "==" || op == "!=":
+		if left != kindAny && right != kindAny && left != right {
+			return fmt.Errorf("%s compares values of different types", op)
+		}
+	default:
+		if left == kindBool || right == kindBool {
+			return fmt.Errorf("%s cannot order booleans", op)
+		}
+		if left != kindAny && right != kindAny && left != right {
+			return fmt.Errorf("%s compares values of different types", op)
+		}
+	}
+	return nil
+}
+
+func (p *conditionParser) parseOperand() (conditionNode, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case token.typ == tokenNumber || token.typ == tokenString:
+		return p.node(literalNode{value: token.value})
+	case token.typ == tokenIdent && (token.text == "true" || token.text == "false"):
+		return p.node(literalNode{value: token.text == "true"})
+	case token.typ == tokenIdent && token.text == "in":
+		return nil, fmt.Errorf("unexpected in")
+	case token.typ == tokenIdent:
+		path := strings.Split(token.text, ".")
+		if slices.Contains(path, "") {
+			return nil, fmt.Errorf("invalid attribute path %q", token.text)
+		}
+		return p.node(pathNode{path: path})
+	case token.text == "(":
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return inner, nil
+	case token.text == "[":
+		return p.parseList()
+	default:
+		return nil, fmt.Errorf("unexpected %q", token.text)
+	}
+}
+
+func (p *conditionParser) parseList() (conditionNode, error) {
+	list := []any{}
+	for !p.peek("]") {
+		if len(list) > 0 {
+			if !p.peek(",") {
+				return nil, fmt.Errorf("missing , or ] in list")
+			}
+			p.pos++
+		}
+		item, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		literal, ok := item.(literalNode)
+		if !ok || literal.kind() == kindList {
+			return nil, fmt.Errorf("lists may only hold literals")
+		}
+		list = append(list, literal.value)
+	}
+	p.pos++
+	return p.node(literalNode{value: list})
+}
+
+func isBoolish(node conditionNode) bool {
+	return node.kind() == kindBool || node.kind() == kindAny
+}
+
+type conditionCache struct {
+	mu       sync.Mutex
+	compiled map[string]*condition
+}
+
+// The cache is reset when full.
+const maxCachedConditions = 1024
+
+func (c *conditionCache) get(expr string) *condition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if compiled, ok := c.compiled[expr]; ok {
+		return compiled
+	}
+	if c.compiled == nil || len(c.compiled) >= maxCachedConditions {
+		c.compiled = map[string]*condition{}
+	}
+	compiled, err := compileCondition(expr)
+	if err != nil {
+		compiled = nil
+	}
+	c.compiled[expr] = compiled
+	return compiled
+}
+
+func (c *conditionCache) holds(expr string, attrs map[string]any) bool {
+	compiled := c.get(expr)
+	return compiled != nil && compiled.holds(attrs)
+}
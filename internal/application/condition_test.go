@@ -0,0 +1,87 @@
+package application
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func TestConditionHolds(t *testing.T) {
+	attrs := map[string]any{
+		"amount":  2500.0,
+		"hour":    10,
+		"region":  "eu",
+		"tags":    []any{"vip", "beta"},
+		"request": map[string]any{"external": false, "ip": "10.0.0.1"},
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"amount < 10000", true},
+		{"amount < 10000 && hour >= 9 && hour < 17", true},
+		{"amount >= 10000 || hour > 17", false},
+		{"!(amount >= 10000)", true},
+		{"region == 'eu'", true},
+		{`region != "eu"`, false},
+		{"region in ['eu', 'us']", true},
+		{"region in ['ap']", false},
+		{"'vip' in tags", true},
+		{"!request.external && request.ip == '10.0.0.1'", true},
+		{"request.external", false},
+		{"amount == -1", false},
+		{"true", true},
+		{"missing < 3", false},
+		{"missing < 3 || amount < 10000", false},
+		{"amount < 10000 || missing < 3", true},
+		{"region < 10", false},
+		{"request.ip.octet == 1", false},
+	}
+	for _, tt := range tests {
+		compiled, err := compileCondition(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.want, compiled.holds(attrs), tt.expr)
+	}
+}
+
+func TestTokenizeConditionRejectsNonASCII(t *testing.T) {
+	for _, expr := range []string{"montant_é == 1", "é == 1", "größe < 3"} {
+		_, err := tokenizeCondition(expr)
+		require.Error(t, err, expr)
+		assert.Contains(t, err.Error(), "unexpected character", expr)
+	}
+	_, err := tokenizeCondition("é == 1")
+	assert.ErrorContains(t, err, `'é'`)
+}
+
+func TestCompileConditionRejects(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"amount <",
+		"amount < 10000 &&",
+		"(amount < 10000",
+		"amount < 10000)",
+		"10000",
+		"'text'",
+		"10 < 'ten'",
+		"true < false",
+		"[1, 2] == tags",
+		"region in 'eu'",
+		"region in [tags]",
+		"1 && true",
+		"!1",
+		"amount # 3",
+		"'unterminated",
+		"a..b == 1",
+		"len(tags) > 1",
+		strings.Repeat("a == 1 || ", 40) + "a == 1",
+		strings.Repeat("(", 20) + "a" + strings.Repeat(")", 20),
+		strings.Repeat("x", maxConditionLength+1),
+	} {
+		_, err := compileCondition(expr)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput, expr)
+	}
+}
@@ -0,0 +1,236 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+	"slices"
+	"strings"
+	"time"
+)
+
+type GroupService struct {
+	repo     ports.GroupRepository
+	roleRepo ports.RoleRepository
+	logger   ports.Logger
+}
+
+func NewGroupService(repo ports.GroupRepository, roleRepo ports.RoleRepository, logger ...ports.Logger) *GroupService {
+	return &GroupService{repo: repo, roleRepo: roleRepo, logger: resolveLogger(logger)}
+}
+
+func (s *GroupService) checkRoles(ctx context.Context, appID, groupID string, roleIDs []string) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	roles, err := s.roleRepo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for group", "app_id", appID, "group_id", groupID, "error", err)
+		return err
+	}
+	idx := indexRoles(roles)
+	var unknown []string
+	for _, roleID := range roleIDs {
+		if _, ok := idx[roleID]; !ok {
+			unknown = append(unknown, roleID)
+		}
+	}
+	if len(unknown) > 0 {
+		s.logger.Warn(ctx, "group references unknown roles", "app_id", appID, "group_id", groupID, "roles", unknown)
+		return fmt.Errorf("%w: unknown roles: %s", domain.ErrUnprocessable, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func (s *GroupService) Create(ctx context.Context, group domain.Group) error {
+	if group.AppID == "" || group.ID == "" || group.Name == "" {
+		s.logger.Warn(ctx, "invalid group create input", "app_id", group.AppID, "group_id", group.ID)
+		return domain.ErrInvalidInput
+	}
+	if err := domain.ValidateID(group.ID); err != nil {
+		s.logger.Warn(ctx, "invalid group id", "app_id", group.AppID, "group_id", group.ID)
+		return err
+	}
+	group.Roles = slices.Compact(slices.Sorted(slices.Values(group.Roles)))
+	if err := s.checkRoles(ctx, group.AppID, group.ID, group.Roles); err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	group.CreatedAt = now
+	group.UpdatedAt = now
+	if err := s.repo.Create(ctx, group); err != nil {
+		s.logger.Error(ctx, "failed to create group", "app_id", group.AppID, "group_id", group.ID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "group created", "app_id", group.AppID, "group_id", group.ID)
+	return nil
+}
+
+func (s *GroupService) Update(ctx context.Context, group domain.Group) error {
+	if group.AppID == "" || group.ID == "" || group.Name == "" {
+		s.logger.Warn(ctx, "invalid group update input", "app_id", group.AppID, "group_id", group.ID)
+		return domain.ErrInvalidInput
+	}
+	group.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, group); err != nil {
+		s.logger.Error(ctx, "failed to update group", "app_id", group.AppID, "group_id", group.ID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "group updated", "app_id", group.AppID, "group_id", group.ID)
+	return nil
+}
+
+func (s *GroupService) Get(ctx context.Context, appID, groupID string) (domain.Group, error) {
+	if appID == "" || groupID == "" {
+		s.logger.Warn(ctx, "invalid group get input", "app_id", appID, "group_id", groupID)
+		return domain.Group{}, domain.ErrInvalidInput
+	}
+	group, err := s.repo.Get(ctx, appID, groupID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get group", "app_id", appID, "group_id", groupID, "error", err)
+		return domain.Group{}, err
+	}
+	return group, nil
+}
+
+func (s *GroupService) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Group], error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid groups query", "app_id", appID)
+		return domain.Page[domain.Group]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid groups page", "app_id", appID, "limit", page.Limit)
+		return domain.Page[domain.Group]{}, err
+	}
+	groups, err := s.repo.ListByAppID(ctx, appID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list groups", "app_id", appID, "error", err)
+		return domain.Page[domain.Group]{}, err
+	}
+	s.logger.Debug(ctx, "groups listed", "app_id", appID, "count", len(groups.Items))
+	return groups, nil
+}
+
+func (s *GroupService) Delete(ctx context.Context, appID, groupID string) error {
+	if appID == "" || groupID == "" {
+		s.logger.Warn(ctx, "invalid group delete input", "app_id", appID, "group_id", groupID)
+		return domain.ErrInvalidInput
+	}
+	if _, err := s.repo.Get(ctx, appID, groupID); err != nil {
+		s.logger.Error(ctx, "failed to get group for delete", "app_id", appID, "group_id", groupID, "error", err)
+		return err
+	}
+	removed := 0
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		members, err := s.repo.ListMembers(ctx, appID, groupID, page)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list group members for delete", "app_id", appID, "group_id", groupID, "error", err)
+			return err
+		}
+		for _, member := range members.Items {
+			err := s.repo.RemoveMember(ctx, appID, groupID, member.UserID)
+			if err != nil && !errors.Is(err, domain.ErrNotFound) {
+				s.logger.Error(ctx, "failed to remove group member for delete", "app_id", appID, "group_id", groupID, "user_id", member.UserID, "error", err)
+				return err
+			}
+			removed++
+		}
+		if members.NextToken == "" {
+			break
+		}
+		page.NextToken = members.NextToken
+	}
+	if err := s.repo.Delete(ctx, appID, groupID); err != nil {
+		s.logger.Error(ctx, "failed to delete group", "app_id", appID, "group_id", groupID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "group deleted", "app_id", appID, "group_id", groupID, "members_removed", removed)
+	return nil
+}
+
+func (s *GroupService) AssignRole(ctx context.Context, appID, groupID, roleID string) error {
+	if appID == "" || groupID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid group assign role input", "app_id", appID, "group_id", groupID, "role_id", roleID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.checkRoles(ctx, appID, groupID, []string{roleID}); err != nil {
+		return err
+	}
+	if err := s.repo.AssignRole(ctx, appID, groupID, roleID); err != nil {
+		s.logger.Error(ctx, "failed to assign role to group", "app_id", appID, "group_id", groupID, "role_id", roleID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "role assigned to group", "app_id", appID, "group_id", groupID, "role_id", roleID)
+	return nil
+}
+
+func (s *GroupService) RevokeRole(ctx context.Context, appID, groupID, roleID string) error {
+	if appID == "" || groupID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid group revoke role input", "app_id", appID, "group_id", groupID, "role_id", roleID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.repo.RevokeRole(ctx, appID, groupID, roleID); err != nil {
+		s.logger.Error(ctx, "failed to revoke role from group", "app_id", appID, "group_id", groupID, "role_id", roleID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "role revoked from group", "app_id", appID, "group_id", groupID, "role_id", roleID)
+	return nil
+}
+
+func (s *GroupService) AddMember(ctx context.Context, appID, groupID, userID string) error {
+	if appID == "" || groupID == "" || userID == "" {
+		s.logger.Warn(ctx, "invalid group add member input", "app_id", appID, "group_id", groupID, "user_id", userID)
+		return domain.ErrInvalidInput
+	}
+	if err := domain.ValidateID(userID); err != nil {
+		s.logger.Warn(ctx, "invalid group member id", "app_id", appID, "group_id", groupID, "user_id", userID)
+		return err
+	}
+	err := s.repo.AddMember(ctx, appID, groupID, userID)
+	if errors.Is(err, domain.ErrConflict) {
+		s.logger.Warn(ctx, "user is in too many groups", "app_id", appID, "group_id", groupID, "user_id", userID)
+		return fmt.Errorf("%w: user %s is already in %d groups", domain.ErrConflict, userID, domain.MaxGroupsPerUser)
+	}
+	if err != nil {
+		s.logger.Error(ctx, "failed to add group member", "app_id", appID, "group_id", groupID, "user_id", userID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "group member added", "app_id", appID, "group_id", groupID, "user_id", userID)
+	return nil
+}
+
+func (s *GroupService) RemoveMember(ctx context.Context, appID, groupID, userID string) error {
+	if appID == "" || groupID == "" || userID == "" {
+		s.logger.Warn(ctx, "invalid group remove member input", "app_id", appID, "group_id", groupID, "user_id", userID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.repo.RemoveMember(ctx, appID, groupID, userID); err != nil {
+		s.logger.Error(ctx, "failed to remove group member", "app_id", appID, "group_id", groupID, "user_id", userID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "group member removed", "app_id", appID, "group_id", groupID, "user_id", userID)
+	return nil
+}
+
+func (s *GroupService) ListMembers(ctx context.Context, appID, groupID string, page domain.PageRequest) (domain.Page[domain.GroupMember], error) {
+	if appID == "" || groupID == "" {
+		s.logger.Warn(ctx, "invalid group members query", "app_id", appID, "group_id", groupID)
+		return domain.Page[domain.GroupMember]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid group members page", "app_id", appID, "group_id", groupID, "limit", page.Limit)
+		return domain.Page[domain.GroupMember]{}, err
+	}
+	members, err := s.repo.ListMembers(ctx, appID, groupID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list group members", "app_id", appID, "group_id", groupID, "error", err)
+		return domain.Page[domain.GroupMember]{}, err
+	}
+	s.logger.Debug(ctx, "group members listed", "app_id", appID, "group_id", groupID, "count", len(members.Items))
+	return members, nil
+}
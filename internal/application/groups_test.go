@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func TestGroupService_Create(t *testing.T) {
+	repo := new(groupRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewGroupService(repo, roleRepo)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(group domain.Group) bool {
+		return group.ID == "ops" && assert.ObjectsAreEqual([]string{"editor", "viewer"}, group.Roles) && !group.CreatedAt.IsZero()
+	})).Return(nil)
+
+	err := svc.Create(context.Background(), domain.Group{AppID: "a1", ID: "ops", Name: "Ops", Roles: []string{"viewer", "editor", "viewer"}})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestGroupService_CreateRejectsUnknownRoles(t *testing.T) {
+	repo := new(groupRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewGroupService(repo, roleRepo)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.Create(context.Background(), domain.Group{AppID: "a1", ID: "ops", Name: "Ops", Roles: []string{"viewer", "ghost"}})
+	require.ErrorIs(t, err, domain.ErrUnprocessable)
+	assert.Contains(t, err.Error(), "ghost")
+
+	err = svc.Create(context.Background(), domain.Group{AppID: "a1", ID: "ops"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestGroupService_AssignRoleRejectsUnknownRole(t *testing.T) {
+	repo := new(groupRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewGroupService(repo, roleRepo)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.AssignRole(context.Background(), "a1", "ops", "ghost")
+	assert.ErrorIs(t, err, domain.ErrUnprocessable)
+	repo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGroupService_RoleAndMemberWrites(t *testing.T) {
+	repo := new(groupRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewGroupService(repo, roleRepo)
+	ctx := context.Background()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+	repo.On("AssignRole", mock.Anything, "a1", "ops", "editor").Return(nil).Once()
+	repo.On("RevokeRole", mock.Anything, "a1", "ops", "editor").Return(domain.ErrNotFound).Once()
+	repo.On("AddMember", mock.Anything, "a1", "ops", "u1").Return(nil).Once()
+	repo.On("RemoveMember", mock.Anything, "a1", "ops", "u1").Return(nil).Once()
+
+	require.NoError(t, svc.AssignRole(ctx, "a1", "ops", "editor"))
+	assert.ErrorIs(t, svc.RevokeRole(ctx, "a1", "ops", "editor"), domain.ErrNotFound)
+	require.NoError(t, svc.AddMember(ctx, "a1", "ops", "u1"))
+	require.NoError(t, svc.RemoveMember(ctx, "a1", "ops", "u1"))
+	assert.ErrorIs(t, svc.AssignRole(ctx, "a1", "", "editor"), domain.ErrInvalidInput)
+	assert.ErrorIs(t, svc.RevokeRole(ctx, "a1", "ops", ""), domain.ErrInvalidInput)
+	assert.ErrorIs(t, svc.AddMember(ctx, "", "ops", "u1"), domain.ErrInvalidInput)
+	assert.ErrorIs(t, svc.RemoveMember(ctx, "a1", "ops", ""), domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+}
+
+func TestGroupService_Reads(t *testing.T) {
+	repo := new(groupRepoMock)
+	svc := NewGroupService(repo, new(roleRepoMock))
+	ctx := context.Background()
+	group := domain.Group{AppID: "a1", ID: "ops", Name: "Ops", Roles: []string{"viewer"}}
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(g domain.Group) bool {
+		return g.ID == "ops" && g.Name == "Operations" && !g.UpdatedAt.IsZero()
+	})).Return(nil).Once()
+	repo.On("Get", mock.Anything, "a1", "ops").Return(group, nil).Once()
+	repo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.DefaultPageLimit}).
+		Return(domain.Page[domain.Group]{Items: []domain.Group{group}}, nil).Once()
+	repo.On("ListMembers", mock.Anything, "a1", "ops", domain.PageRequest{Limit: 10}).
+		Return(domain.Page[domain.GroupMember]{Items: []domain.GroupMember{{AppID: "a1", GroupID: "ops", UserID: "u1"}}}, nil).Once()
+
+	require.NoError(t, svc.Update(ctx, domain.Group{AppID: "a1", ID: "ops", Name: "Operations"}))
+	assert.ErrorIs(t, svc.Update(ctx, domain.Group{AppID: "a1", ID: "ops"}), domain.ErrInvalidInput)
+
+	got, err := svc.Get(ctx, "a1", "ops")
+	require.NoError(t, err)
+	assert.Equal(t, group, got)
+	_, err = svc.Get(ctx, "a1", "")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	groups, err := svc.ListByAppID(ctx, "a1", domain.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, groups.Items, 1)
+	_, err = svc.ListByAppID(ctx, "a1", domain.PageRequest{Limit: -1})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	members, err := svc.ListMembers(ctx, "a1", "ops", domain.PageRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "u1", members.Items[0].UserID)
+	_, err = svc.ListMembers(ctx, "a1", "", domain.PageRequest{})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+}
+
+func TestGroupService_DeleteRemovesMembers(t *testing.T) {
+	repo := new(groupRepoMock)
+	svc := NewGroupService(repo, new(roleRepoMock))
+	repo.On("Get", mock.Anything, "a1", "ops").Return(domain.Group{AppID: "a1", ID: "ops"}, nil)
+	repo.On("ListMembers", mock.Anything, "a1", "ops", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.GroupMember]{Items: []domain.GroupMember{{UserID: "u1"}}, NextToken: "next"}, nil)
+	repo.On("ListMembers", mock.Anything, "a1", "ops", domain.PageRequest{Limit: domain.MaxPageLimit, NextToken: "next"}).
+		Return(domain.Page[domain.GroupMember]{Items: []domain.GroupMember{{UserID: "u2"}}}, nil)
+	repo.On("RemoveMember", mock.Anything, "a1", "ops", "u1").Return(nil)
+	repo.On("RemoveMember", mock.Anything, "a1", "ops", "u2").Return(domain.ErrNotFound)
+	repo.On("Delete", mock.Anything, "a1", "ops").Return(nil)
+
+	require.NoError(t, svc.Delete(context.Background(), "a1", "ops"))
+	repo.AssertExpectations(t)
+}
+
+func TestGroupService_AddMemberOverLimit(t *testing.T) {
+	repo := new(groupRepoMock)
+	svc := NewGroupService(repo, new(roleRepoMock))
+	repo.On("AddMember", mock.Anything, "a1", "ops", "u1").Return(domain.ErrConflict)
+
+	err := svc.AddMember(context.Background(), "a1", "ops", "u1")
+	require.ErrorIs(t, err, domain.ErrConflict)
+	assert.Contains(t, err.Error(), "100 groups")
+}
+
+func TestAuthorizationService_GroupRoles(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	groupRepo := new(groupRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, groupRepo, new(claimMappingRepoMock))
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").
+		Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"viewer"}, Groups: []string{"gone", "writers"}}, nil).Once()
+	groupRepo.On("GetMany", mock.Anything, "a1", []string{"gone", "writers"}).
+		Return([]domain.Group{{AppID: "a1", ID: "writers", Roles: []string{"editor"}}}, nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil).Once()
+
+	decisions, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:write"},
+		{AppID: "a1", UserID: "u1", Permission: "doc:delete"},
+	})
+	require.NoError(t, err)
+	assert.True(t, decisions[0].Allowed)
+	assert.False(t, decisions[1].Allowed)
+	userRepo.AssertExpectations(t)
+	groupRepo.AssertExpectations(t)
+	roleRepo.AssertExpectations(t)
+}
+
+func TestAuthorizationService_ExplainGroupOnlyUser(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	groupRepo := new(groupRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, groupRepo, new(claimMappingRepoMock))
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").
+		Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{}, Groups: []string{"readers"}}, nil)
+	groupRepo.On("GetMany", mock.Anything, "a1", []string{"readers"}).
+		Return([]domain.Group{{AppID: "a1", ID: "readers", Roles: []string{"viewer"}}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	got, err := svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:read"})
+	require.NoError(t, err)
+	assert.True(t, got.Allowed)
+	assert.Equal(t, domain.ReasonGranted, got.Reason)
+	assert.Equal(t, []string{"readers"}, got.Groups)
+	assert.Equal(t, []string{"viewer"}, got.ActiveRoles)
+}
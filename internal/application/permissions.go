@@ -0,0 +1,170 @@
+package application
+
+import (
+	"rbac-project/internal/domain"
+	"slices"
+)
+
+type roleIndex map[string]domain.Role
+
+func indexRoles(roles []domain.Role) roleIndex {
+	idx := make(roleIndex, len(roles))
+	for _, role := range roles {
+		idx[role.ID] = role
+	}
+	return idx
+}
+
+// expand returns the given role IDs followed by all of their ancestors, each
+// once.
+func (idx roleIndex) expand(roleIDs []string) []string {
+	seen := map[string]bool{}
+	var out []string
+	var walk func(roleID string)
+	walk = func(roleID string) {
+		role, ok := idx[roleID]
+		if !ok || seen[roleID] {
+			return
+		}
+		seen[roleID] = true
+		out = append(out, roleID)
+		for _, parent := range role.Parents {
+			walk(parent)
+		}
+	}
+	for _, roleID := range roleIDs {
+		walk(roleID)
+	}
+	return out
+}
+
+func (idx roleIndex) permissions(roleIDs []string) []string {
+	seen := map[string]bool{}
+	permissions := []string{}
+	for _, roleID := range idx.expand(roleIDs) {
+		for _, permission := range idx[roleID].Permissions {
+			if !seen[permission] {
+				seen[permission] = true
+				permissions = append(permissions, permission)
+			}
+		}
+	}
+	return permissions
+}
+
+// cycle returns a path from roleID back to itself, or nil.
+func (idx roleIndex) cycle(roleID string) []string {
+	visited := map[string]bool{}
+	path := []string{}
+	var walk func(current string) bool
+	walk = func(current string) bool {
+		path = append(path, current)
+		for _, parent := range idx[current].Parents {
+			if parent == roleID {
+				path = append(path, parent)
+				return true
+			}
+			if !visited[parent] {
+				visited[parent] = true
+				if walk(parent) {
+					return true
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if walk(roleID) {
+		return path
+	}
+	return nil
+}
+
+func (idx roleIndex) grants(roleIDs []string) []domain.PermissionGrant {
+	var grants []domain.PermissionGrant
+	for _, roleID := range idx.expand(roleIDs) {
+		role := idx[roleID]
+		for _, pattern := range role.Permissions {
+			grants = append(grants, domain.PermissionGrant{RoleID: roleID, Pattern: pattern, Condition: role.Conditions[pattern]})
+		}
+	}
+	return grants
+}
+
+func (idx roleIndex) denyRules(roleIDs []string) []domain.DenyRule {
+	var rules []domain.DenyRule
+	for _, roleID := range idx.expand(roleIDs) {
+		for _, pattern := range idx[roleID].Deny {
+			rules = append(rules, domain.DenyRule{RoleID: roleID, Pattern: pattern})
+		}
+	}
+	return rules
+}
+
+func (idx roleIndex) withEffectivePermissions(roles []domain.Role) []domain.Role {
+	for i := range roles {
+		roles[i].EffectivePermissions = idx.permissions([]string{roles[i].ID})
+		roles[i].EffectiveDeny = nil
+		for _, rule := range idx.denyRules([]string{roles[i].ID}) {
+			if !slices.Contains(roles[i].EffectiveDeny, rule.Pattern) {
+				roles[i].EffectiveDeny = append(roles[i].EffectiveDeny, rule.Pattern)
+			}
+		}
+	}
+	return roles
+}
+
+// decide lets a matching deny rule win over any grant.
+func (idx roleIndex) decide(assigned []string, permission string, conditionHolds func(expr string) bool) domain.Decision {
+	explanation := idx.explain(assigned, permission, conditionHolds)
+	decision := domain.Decision{Allowed: explanation.Allowed, DeniedBy: explanation.DeniedBy}
+	if decision.DeniedBy != nil {
+		decision.Reason = decision.DeniedBy.Err().Error()
+	}
+	return decision
+}
+
+func (idx roleIndex) explain(assigned []string, permission string, conditionHolds func(expr string) bool) domain.Explanation {
+	explanation := domain.Explanation{EvaluatedRoles: idx.expand(assigned)}
+	for _, roleID := range assigned {
+		if _, ok := idx[roleID]; !ok && !slices.Contains(explanation.MissingRoles, roleID) {
+			explanation.MissingRoles = append(explanation.MissingRoles, roleID)
+		}
+	}
+	for _, roleID := range explanation.EvaluatedRoles {
+		for _, parent := range idx[roleID].Parents {
+			if _, ok := idx[parent]; !ok && !slices.Contains(explanation.MissingRoles, parent) {
+				explanation.MissingRoles = append(explanation.MissingRoles, parent)
+			}
+		}
+	}
+	for _, rule := range idx.denyRules(assigned) {
+		if domain.MatchPermission(rule.Pattern, permission) {
+			explanation.DeniedBy = &rule
+			explanation.Reason = domain.ReasonDeniedByRule
+			return explanation
+		}
+	}
+	for _, grant := range idx.grants(assigned) {
+		if !domain.MatchPermission(grant.Pattern, permission) {
+			continue
+		}
+		if grant.Condition != "" && !conditionHolds(grant.Condition) {
+			explanation.UnmetConditions = append(explanation.UnmetConditions, grant)
+			continue
+		}
+		explanation.Allowed = true
+		explanation.GrantedBy = &grant
+		explanation.Reason = domain.ReasonGranted
+		return explanation
+	}
+	switch {
+	case len(explanation.UnmetConditions) > 0:
+		explanation.Reason = domain.ReasonConditionNotMet
+	case len(explanation.EvaluatedRoles) == 0:
+		explanation.Reason = domain.ReasonDanglingRoles
+	default:
+		explanation.Reason = domain.ReasonNotGranted
+	}
+	return explanation
+}
@@ -3,9 +3,11 @@ package application
 import (
 	"context"
 	"errors"
+	"fmt"
 	"rbac-project/internal/domain"
 	"rbac-project/internal/ports"
 	"slices"
+	"strings"
 	"time"
 )
 
@@ -23,13 +25,24 @@ func resolveLogger(logger []ports.Logger) ports.Logger {
 	return noopLogger{}
 }
 
+func normalizePage(page domain.PageRequest) (domain.PageRequest, error) {
+	if page.Limit < 0 || page.Limit > domain.MaxPageLimit {
+		return page, domain.ErrInvalidInput
+	}
+	if page.Limit == 0 {
+		page.Limit = domain.DefaultPageLimit
+	}
+	return page, nil
+}
+
 type ApplicationService struct {
-	repo   ports.ApplicationRepository
-	logger ports.Logger
+	repo     ports.ApplicationRepository
+	userRepo ports.UserRoleRepository
+	logger   ports.Logger
 }
 
-func NewApplicationService(repo ports.ApplicationRepository, logger ...ports.Logger) *ApplicationService {
-	return &ApplicationService{repo: repo, logger: resolveLogger(logger)}
+func NewApplicationService(repo ports.ApplicationRepository, userRepo ports.UserRoleRepository, logger ...ports.Logger) *ApplicationService {
+	return &ApplicationService{repo: repo, userRepo: userRepo, logger: resolveLogger(logger)}
 }
 
 func (s *ApplicationService) Create(ctx context.Context, app domain.Application) error {
@@ -37,6 +50,18 @@ func (s *ApplicationService) Create(ctx context.Context, app domain.Application)
 		s.logger.Warn(ctx, "invalid application create input", "app_id", app.ID)
 		return domain.ErrInvalidInput
 	}
+	if err := domain.ValidateAppID(app.ID); err != nil {
+		s.logger.Warn(ctx, "invalid application id", "app_id", app.ID)
+		return err
+	}
+	if app.ID == domain.AdminAppID {
+		s.logger.Warn(ctx, "reserved application id", "app_id", app.ID)
+		return fmt.Errorf("%w: application id %s is reserved", domain.ErrConflict, app.ID)
+	}
+	if app.StrictMode == nil {
+		strict := true
+		app.StrictMode = &strict
+	}
 	now := time.Now().UTC()
 	app.CreatedAt = now
 	app.UpdatedAt = now
@@ -54,6 +79,10 @@ func (s *ApplicationService) Update(ctx context.Context, app domain.Application)
 		s.logger.Warn(ctx, "invalid application update input", "app_id", app.ID)
 		return domain.ErrInvalidInput
 	}
+	if app.ID == domain.AdminAppID {
+		s.logger.Warn(ctx, "reserved application update refused", "app_id", app.ID)
+		return fmt.Errorf("%w: application %s is reserved", domain.ErrConflict, app.ID)
+	}
 	app.UpdatedAt = time.Now().UTC()
 	err := s.repo.Update(ctx, app)
 	if err != nil {
@@ -78,13 +107,158 @@ func (s *ApplicationService) GetByID(ctx context.Context, appID string) (domain.
 	return app, nil
 }
 
+func (s *ApplicationService) List(ctx context.Context, query domain.ApplicationQuery) (domain.Page[domain.Application], error) {
+	page, err := normalizePage(query.Page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid application list page", "limit", query.Page.Limit)
+		return domain.Page[domain.Application]{}, err
+	}
+	query.Page = page
+	apps, err := s.repo.List(ctx, query)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list applications", "name_prefix", query.NamePrefix, "error", err)
+		return domain.Page[domain.Application]{}, err
+	}
+	s.logger.Debug(ctx, "applications listed", "count", len(apps.Items))
+	return apps, nil
+}
+
+// Delete resumes from nextToken until the result is Completed.
+func (s *ApplicationService) Delete(ctx context.Context, appID string, force bool, nextToken string) (domain.ApplicationDeletion, error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid application delete input", "app_id", appID)
+		return domain.ApplicationDeletion{}, domain.ErrInvalidInput
+	}
+	if appID == domain.AdminAppID {
+		s.logger.Warn(ctx, "reserved application delete refused", "app_id", appID)
+		return domain.ApplicationDeletion{}, fmt.Errorf("%w: application %s is reserved", domain.ErrConflict, appID)
+	}
+	if _, err := s.repo.GetByID(ctx, appID); err != nil {
+		s.logger.Error(ctx, "failed to get application for delete", "app_id", appID, "error", err)
+		return domain.ApplicationDeletion{}, err
+	}
+	if !force {
+		assigned, err := s.hasAssignments(ctx, appID)
+		if err != nil {
+			s.logger.Error(ctx, "failed to check application assignments", "app_id", appID, "error", err)
+			return domain.ApplicationDeletion{}, err
+		}
+		if assigned {
+			s.logger.Warn(ctx, "application delete refused: users still assigned", "app_id", appID)
+			return domain.ApplicationDeletion{}, fmt.Errorf("%w: application has user assignments, set force to delete them", domain.ErrConflict)
+		}
+	}
+	result, err := s.repo.Delete(ctx, appID, nextToken)
+	if err != nil {
+		s.logger.Error(ctx, "failed to delete application", "app_id", appID, "error", err)
+		return domain.ApplicationDeletion{}, err
+	}
+	s.logger.Info(ctx, "application delete progressed", "app_id", appID, "completed", result.Completed,
+		"roles", result.RolesRemoved, "permissions", result.PermissionsRemoved, "assignments", result.AssignmentsRemoved)
+	return result, nil
+}
+
+// Assignments emptied by revokes are left behind and do not count.
+func (s *ApplicationService) hasAssignments(ctx context.Context, appID string) (bool, error) {
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		assigned, err := s.userRepo.ListByAppID(ctx, appID, page)
+		if err != nil {
+			return false, err
+		}
+		for _, userRoles := range assigned.Items {
+			if len(userRoles.Roles) > 0 || len(userRoles.Groups) > 0 {
+				return true, nil
+			}
+		}
+		if assigned.NextToken == "" {
+			return false, nil
+		}
+		page.NextToken = assigned.NextToken
+	}
+}
+
 type RoleService struct {
-	repo   ports.RoleRepository
-	logger ports.Logger
+	repo        ports.RoleRepository
+	userRepo    ports.UserRoleRepository
+	permRepo    ports.PermissionRepository
+	appRepo     ports.ApplicationRepository
+	groupRepo   ports.GroupRepository
+	mappingRepo ports.ClaimMappingRepository
+	logger      ports.Logger
+}
+
+func NewRoleService(repo ports.RoleRepository, userRepo ports.UserRoleRepository, permRepo ports.PermissionRepository, appRepo ports.ApplicationRepository, groupRepo ports.GroupRepository, mappingRepo ports.ClaimMappingRepository, logger ...ports.Logger) *RoleService {
+	return &RoleService{repo: repo, userRepo: userRepo, permRepo: permRepo, appRepo: appRepo, groupRepo: groupRepo, mappingRepo: mappingRepo, logger: resolveLogger(logger)}
+}
+
+func (s *RoleService) checkPermissions(ctx context.Context, role domain.Role) error {
+	for _, pattern := range slices.Concat(role.Permissions, role.Deny) {
+		if err := domain.ValidatePermissionPattern(pattern); err != nil {
+			s.logger.Warn(ctx, "invalid role permission pattern", "app_id", role.AppID, "role_id", role.ID, "permission", pattern)
+			return err
+		}
+	}
+	for pattern, expr := range role.Conditions {
+		if !slices.Contains(role.Permissions, pattern) {
+			s.logger.Warn(ctx, "condition on ungranted permission", "app_id", role.AppID, "role_id", role.ID, "permission", pattern)
+			return fmt.Errorf("%w: condition on %q, which the role does not grant", domain.ErrInvalidInput, pattern)
+		}
+		if _, err := compileCondition(expr); err != nil {
+			s.logger.Warn(ctx, "invalid role condition", "app_id", role.AppID, "role_id", role.ID, "permission", pattern, "error", err)
+			return err
+		}
+	}
+	if len(role.Permissions) == 0 {
+		return nil
+	}
+	app, err := s.appRepo.GetByID(ctx, role.AppID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to get application for role", "app_id", role.AppID, "role_id", role.ID, "error", err)
+		return err
+	}
+	if !app.IsStrict() {
+		return nil
+	}
+	permissions, err := s.permRepo.ListByAppID(ctx, role.AppID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list permissions for role", "app_id", role.AppID, "role_id", role.ID, "error", err)
+		return err
+	}
+	var unknown []string
+	for _, pattern := range role.Permissions {
+		matched := slices.ContainsFunc(permissions, func(permission domain.Permission) bool {
+			return domain.MatchPermission(pattern, permission.ID)
+		})
+		if !matched && !slices.Contains(unknown, pattern) {
+			unknown = append(unknown, pattern)
+		}
+	}
+	if len(unknown) > 0 {
+		s.logger.Warn(ctx, "role references unknown permissions", "app_id", role.AppID, "role_id", role.ID, "permissions", unknown)
+		return &domain.UnknownPermissionsError{Permissions: unknown}
+	}
+	return nil
 }
 
-func NewRoleService(repo ports.RoleRepository, logger ...ports.Logger) *RoleService {
-	return &RoleService{repo: repo, logger: resolveLogger(logger)}
+func (s *RoleService) checkParents(ctx context.Context, role domain.Role, roles []domain.Role) error {
+	idx := indexRoles(roles)
+	var unknown []string
+	for _, parent := range role.Parents {
+		if _, ok := idx[parent]; !ok && parent != role.ID {
+			unknown = append(unknown, parent)
+		}
+	}
+	if len(unknown) > 0 {
+		s.logger.Warn(ctx, "role references unknown parents", "app_id", role.AppID, "role_id", role.ID, "parents", unknown)
+		return fmt.Errorf("%w: unknown parent roles: %s", domain.ErrUnprocessable, strings.Join(unknown, ", "))
+	}
+	idx[role.ID] = role
+	if path := idx.cycle(role.ID); path != nil {
+		s.logger.Warn(ctx, "role hierarchy cycle rejected", "app_id", role.AppID, "role_id", role.ID, "cycle", path)
+		return fmt.Errorf("%w: role hierarchy cycle: %s", domain.ErrUnprocessable, strings.Join(path, " -> "))
+	}
+	return nil
 }
 
 func (s *RoleService) Create(ctx context.Context, role domain.Role) error {
@@ -92,6 +266,23 @@ func (s *RoleService) Create(ctx context.Context, role domain.Role) error {
 		s.logger.Warn(ctx, "invalid role create input", "app_id", role.AppID, "role_id", role.ID)
 		return domain.ErrInvalidInput
 	}
+	if err := domain.ValidateID(role.ID); err != nil {
+		s.logger.Warn(ctx, "invalid role id", "app_id", role.AppID, "role_id", role.ID)
+		return err
+	}
+	if err := s.checkPermissions(ctx, role); err != nil {
+		return err
+	}
+	if len(role.Parents) > 0 {
+		roles, err := s.repo.ListByAppID(ctx, role.AppID)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list roles for hierarchy check", "app_id", role.AppID, "role_id", role.ID, "error", err)
+			return err
+		}
+		if err := s.checkParents(ctx, role, roles); err != nil {
+			return err
+		}
+	}
 	now := time.Now().UTC()
 	role.CreatedAt = now
 	role.UpdatedAt = now
@@ -104,18 +295,44 @@ func (s *RoleService) Create(ctx context.Context, role domain.Role) error {
 	return nil
 }
 
-func (s *RoleService) Update(ctx context.Context, role domain.Role) error {
-	if role.AppID == "" || role.ID == "" || role.Name == "" {
-		s.logger.Warn(ctx, "invalid role update input", "app_id", role.AppID, "role_id", role.ID)
+// Update is conditional on the role and its ancestors being unchanged since
+// the cycle check read them.
+func (s *RoleService) Update(ctx context.Context, appID, roleID string, update domain.RoleUpdate) error {
+	if appID == "" || roleID == "" || (update.Name != nil && *update.Name == "") {
+		s.logger.Warn(ctx, "invalid role update input", "app_id", appID, "role_id", roleID)
 		return domain.ErrInvalidInput
 	}
+	roles, err := s.repo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for update", "app_id", appID, "role_id", roleID, "error", err)
+		return err
+	}
+	idx := indexRoles(roles)
+	current, ok := idx[roleID]
+	if !ok {
+		s.logger.Warn(ctx, "role not found for update", "app_id", appID, "role_id", roleID)
+		return domain.ErrNotFound
+	}
+	role := update.Apply(current)
+	if err := s.checkParents(ctx, role, roles); err != nil {
+		return err
+	}
+	if err := s.checkPermissions(ctx, role); err != nil {
+		return err
+	}
+	unchanged := []domain.Role{current}
+	for _, ancestor := range idx.expand(role.Parents) {
+		if ancestor != roleID {
+			unchanged = append(unchanged, idx[ancestor])
+		}
+	}
 	role.UpdatedAt = time.Now().UTC()
-	err := s.repo.Update(ctx, role)
+	err = s.repo.Update(ctx, role, unchanged)
 	if err != nil {
-		s.logger.Error(ctx, "failed to update role", "app_id", role.AppID, "role_id", role.ID, "error", err)
+		s.logger.Error(ctx, "failed to update role", "app_id", appID, "role_id", roleID, "error", err)
 		return err
 	}
-	s.logger.Info(ctx, "role updated", "app_id", role.AppID, "role_id", role.ID)
+	s.logger.Info(ctx, "role updated", "app_id", appID, "role_id", roleID)
 	return nil
 }
 
@@ -130,16 +347,193 @@ func (s *RoleService) ListByAppID(ctx context.Context, appID string) ([]domain.R
 		return nil, err
 	}
 	s.logger.Debug(ctx, "roles listed", "app_id", appID, "count", len(roles))
+	return indexRoles(roles).withEffectivePermissions(roles), nil
+}
+
+func (s *RoleService) Get(ctx context.Context, appID, roleID string) (domain.Role, error) {
+	if appID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid role get input", "app_id", appID, "role_id", roleID)
+		return domain.Role{}, domain.ErrInvalidInput
+	}
+	roles, err := s.repo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for get", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.Role{}, err
+	}
+	idx := indexRoles(roles)
+	role, ok := idx[roleID]
+	if !ok {
+		s.logger.Warn(ctx, "role not found", "app_id", appID, "role_id", roleID)
+		return domain.Role{}, domain.ErrNotFound
+	}
+	role = idx.withEffectivePermissions([]domain.Role{role})[0]
+	s.logger.Debug(ctx, "role fetched", "app_id", appID, "role_id", roleID)
+	return role, nil
+}
+
+func (s *RoleService) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Role], error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid role list app id", "app_id", appID)
+		return domain.Page[domain.Role]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid role list page", "app_id", appID, "limit", page.Limit)
+		return domain.Page[domain.Role]{}, err
+	}
+	roles, err := s.repo.ListPageByAppID(ctx, appID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles", "app_id", appID, "error", err)
+		return domain.Page[domain.Role]{}, err
+	}
+	idx := indexRoles(roles.Items)
+	if slices.ContainsFunc(roles.Items, func(role domain.Role) bool { return len(role.Parents) > 0 }) {
+		all, err := s.repo.ListByAppID(ctx, appID)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list roles for hierarchy", "app_id", appID, "error", err)
+			return domain.Page[domain.Role]{}, err
+		}
+		idx = indexRoles(all)
+	}
+	roles.Items = idx.withEffectivePermissions(roles.Items)
+	s.logger.Debug(ctx, "roles listed", "app_id", appID, "count", len(roles.Items))
 	return roles, nil
 }
 
+func (s *RoleService) Delete(ctx context.Context, appID, roleID string, dryRun bool) (domain.RoleDeletion, error) {
+	if appID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid role delete input", "app_id", appID, "role_id", roleID)
+		return domain.RoleDeletion{}, domain.ErrInvalidInput
+	}
+	roles, err := s.repo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for delete", "app_id", appID, "error", err)
+		return domain.RoleDeletion{}, err
+	}
+	if !slices.ContainsFunc(roles, func(role domain.Role) bool { return role.ID == roleID }) {
+		s.logger.Warn(ctx, "role not found for delete", "app_id", appID, "role_id", roleID)
+		return domain.RoleDeletion{}, domain.ErrNotFound
+	}
+	userIDs, err := s.usersWithRole(ctx, appID, roleID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to find users with role", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.RoleDeletion{}, err
+	}
+	groupIDs, err := s.groupsWithRole(ctx, appID, roleID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to find groups with role", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.RoleDeletion{}, err
+	}
+	mappings, err := s.mappingRepo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list claim mappings for role delete", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.RoleDeletion{}, err
+	}
+	mappingIDs := []string{}
+	for _, mapping := range mappings {
+		if mapping.RoleID == roleID {
+			mappingIDs = append(mappingIDs, mapping.ID)
+		}
+	}
+	var children []domain.Role
+	childIDs := []string{}
+	for _, role := range roles {
+		if role.ID != roleID && slices.Contains(role.Parents, roleID) {
+			children = append(children, role)
+			childIDs = append(childIDs, role.ID)
+		}
+	}
+	result := domain.RoleDeletion{AppID: appID, RoleID: roleID, DryRun: dryRun, AffectedUsers: len(userIDs), UserIDs: userIDs, ChildRoles: childIDs, Groups: groupIDs, ClaimMappings: mappingIDs}
+	if dryRun {
+		s.logger.Info(ctx, "role delete dry run", "app_id", appID, "role_id", roleID, "affected_users", len(userIDs), "child_roles", len(childIDs), "groups", len(groupIDs), "claim_mappings", len(mappingIDs))
+		return result, nil
+	}
+	for _, mappingID := range mappingIDs {
+		err := s.mappingRepo.Delete(ctx, appID, mappingID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			s.logger.Error(ctx, "failed to delete claim mapping for role", "app_id", appID, "mapping_id", mappingID, "role_id", roleID, "error", err)
+			return domain.RoleDeletion{}, err
+		}
+	}
+	for _, child := range children {
+		updated := child
+		updated.Parents = slices.DeleteFunc(slices.Clone(child.Parents), func(parent string) bool { return parent == roleID })
+		updated.UpdatedAt = time.Now().UTC()
+		if err := s.repo.Update(ctx, updated, []domain.Role{child}); err != nil {
+			s.logger.Error(ctx, "failed to remove parent from role", "app_id", appID, "role_id", child.ID, "parent", roleID, "error", err)
+			return domain.RoleDeletion{}, err
+		}
+	}
+	for _, userID := range userIDs {
+		err := s.userRepo.RevokeRole(ctx, appID, userID, roleID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			s.logger.Error(ctx, "failed to strip role from user", "app_id", appID, "user_id", userID, "role_id", roleID, "error", err)
+			return domain.RoleDeletion{}, err
+		}
+	}
+	for _, groupID := range groupIDs {
+		err := s.groupRepo.RevokeRole(ctx, appID, groupID, roleID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			s.logger.Error(ctx, "failed to strip role from group", "app_id", appID, "group_id", groupID, "role_id", roleID, "error", err)
+			return domain.RoleDeletion{}, err
+		}
+	}
+	if err := s.repo.Delete(ctx, appID, roleID); err != nil {
+		s.logger.Error(ctx, "failed to delete role", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.RoleDeletion{}, err
+	}
+	s.logger.Info(ctx, "role deleted", "app_id", appID, "role_id", roleID, "affected_users", len(userIDs))
+	return result, nil
+}
+
+func (s *RoleService) usersWithRole(ctx context.Context, appID, roleID string) ([]string, error) {
+	userIDs := []string{}
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		assigned, err := s.userRepo.ListByAppID(ctx, appID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, userRoles := range assigned.Items {
+			if slices.Contains(userRoles.Roles, roleID) {
+				userIDs = append(userIDs, userRoles.UserID)
+			}
+		}
+		if assigned.NextToken == "" {
+			return userIDs, nil
+		}
+		page.NextToken = assigned.NextToken
+	}
+}
+
+func (s *RoleService) groupsWithRole(ctx context.Context, appID, roleID string) ([]string, error) {
+	groupIDs := []string{}
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		groups, err := s.groupRepo.ListByAppID(ctx, appID, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups.Items {
+			if slices.Contains(group.Roles, roleID) {
+				groupIDs = append(groupIDs, group.ID)
+			}
+		}
+		if groups.NextToken == "" {
+			return groupIDs, nil
+		}
+		page.NextToken = groups.NextToken
+	}
+}
+
 type PermissionService struct {
-	repo   ports.PermissionRepository
-	logger ports.Logger
+	repo     ports.PermissionRepository
+	roleRepo ports.RoleRepository
+	logger   ports.Logger
 }
 
-func NewPermissionService(repo ports.PermissionRepository, logger ...ports.Logger) *PermissionService {
-	return &PermissionService{repo: repo, logger: resolveLogger(logger)}
+func NewPermissionService(repo ports.PermissionRepository, roleRepo ports.RoleRepository, logger ...ports.Logger) *PermissionService {
+	return &PermissionService{repo: repo, roleRepo: roleRepo, logger: resolveLogger(logger)}
 }
 
 func (s *PermissionService) Create(ctx context.Context, permission domain.Permission) error {
@@ -147,6 +541,10 @@ func (s *PermissionService) Create(ctx context.Context, permission domain.Permis
 		s.logger.Warn(ctx, "invalid permission create input", "app_id", permission.AppID, "permission_id", permission.ID)
 		return domain.ErrInvalidInput
 	}
+	if err := domain.ValidatePermission(permission.ID); err != nil {
+		s.logger.Warn(ctx, "invalid permission id", "app_id", permission.AppID, "permission_id", permission.ID)
+		return err
+	}
 	permission.CreatedAt = time.Now().UTC()
 	err := s.repo.Create(ctx, permission)
 	if err != nil {
@@ -157,6 +555,72 @@ func (s *PermissionService) Create(ctx context.Context, permission domain.Permis
 	return nil
 }
 
+func (s *PermissionService) Update(ctx context.Context, permission domain.Permission) error {
+	if permission.AppID == "" || permission.ID == "" || permission.Name == "" {
+		s.logger.Warn(ctx, "invalid permission update input", "app_id", permission.AppID, "permission_id", permission.ID)
+		return domain.ErrInvalidInput
+	}
+	err := s.repo.Update(ctx, permission)
+	if err != nil {
+		s.logger.Error(ctx, "failed to update permission", "app_id", permission.AppID, "permission_id", permission.ID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "permission updated", "app_id", permission.AppID, "permission_id", permission.ID)
+	return nil
+}
+
+func (s *PermissionService) Delete(ctx context.Context, appID, permissionID string, cascade bool) (domain.PermissionDeletion, error) {
+	if appID == "" || permissionID == "" {
+		s.logger.Warn(ctx, "invalid permission delete input", "app_id", appID, "permission_id", permissionID)
+		return domain.PermissionDeletion{}, domain.ErrInvalidInput
+	}
+	permissions, err := s.repo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list permissions for delete", "app_id", appID, "error", err)
+		return domain.PermissionDeletion{}, err
+	}
+	if !slices.ContainsFunc(permissions, func(p domain.Permission) bool { return p.ID == permissionID }) {
+		s.logger.Warn(ctx, "permission not found for delete", "app_id", appID, "permission_id", permissionID)
+		return domain.PermissionDeletion{}, domain.ErrNotFound
+	}
+	roles, err := s.roleRepo.ListByAppID(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for permission delete", "app_id", appID, "error", err)
+		return domain.PermissionDeletion{}, err
+	}
+	var referencing []domain.Role
+	for _, role := range roles {
+		if slices.Contains(role.Permissions, permissionID) {
+			referencing = append(referencing, role)
+		}
+	}
+	result := domain.PermissionDeletion{AppID: appID, PermissionID: permissionID, RolesUpdated: []string{}}
+	if len(referencing) > 0 && !cascade {
+		roleIDs := make([]string, 0, len(referencing))
+		for _, role := range referencing {
+			roleIDs = append(roleIDs, role.ID)
+		}
+		s.logger.Warn(ctx, "permission delete refused: still granted by roles", "app_id", appID, "permission_id", permissionID, "roles", roleIDs)
+		return domain.PermissionDeletion{}, fmt.Errorf("%w: permission is granted by roles %s", domain.ErrConflict, strings.Join(roleIDs, ", "))
+	}
+	for _, role := range referencing {
+		updated := role
+		updated.Permissions = slices.DeleteFunc(slices.Clone(role.Permissions), func(p string) bool { return p == permissionID })
+		updated.UpdatedAt = time.Now().UTC()
+		if err := s.roleRepo.Update(ctx, updated, []domain.Role{role}); err != nil {
+			s.logger.Error(ctx, "failed to remove permission from role", "app_id", appID, "role_id", role.ID, "permission_id", permissionID, "error", err)
+			return domain.PermissionDeletion{}, err
+		}
+		result.RolesUpdated = append(result.RolesUpdated, role.ID)
+	}
+	if err := s.repo.Delete(ctx, appID, permissionID); err != nil {
+		s.logger.Error(ctx, "failed to delete permission", "app_id", appID, "permission_id", permissionID, "error", err)
+		return domain.PermissionDeletion{}, err
+	}
+	s.logger.Info(ctx, "permission deleted", "app_id", appID, "permission_id", permissionID, "roles_updated", len(result.RolesUpdated))
+	return result, nil
+}
+
 func (s *PermissionService) ListByAppID(ctx context.Context, appID string) ([]domain.Permission, error) {
 	if appID == "" {
 		s.logger.Warn(ctx, "invalid permission list app id", "app_id", appID)
@@ -171,21 +635,71 @@ func (s *PermissionService) ListByAppID(ctx context.Context, appID string) ([]do
 	return permissions, nil
 }
 
+func (s *PermissionService) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Permission], error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid permission list app id", "app_id", appID)
+		return domain.Page[domain.Permission]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid permission list page", "app_id", appID, "limit", page.Limit)
+		return domain.Page[domain.Permission]{}, err
+	}
+	permissions, err := s.repo.ListPageByAppID(ctx, appID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list permissions", "app_id", appID, "error", err)
+		return domain.Page[domain.Permission]{}, err
+	}
+	s.logger.Debug(ctx, "permissions listed", "app_id", appID, "count", len(permissions.Items))
+	return permissions, nil
+}
+
 type UserService struct {
-	userRepo ports.UserRoleRepository
-	roleRepo ports.RoleRepository
-	logger   ports.Logger
+	userRepo  ports.UserRoleRepository
+	roleRepo  ports.RoleRepository
+	groupRepo ports.GroupRepository
+	authz     *AuthorizationService
+	logger    ports.Logger
 }
 
-func NewUserService(userRepo ports.UserRoleRepository, roleRepo ports.RoleRepository, logger ...ports.Logger) *UserService {
-	return &UserService{userRepo: userRepo, roleRepo: roleRepo, logger: resolveLogger(logger)}
+func NewUserService(userRepo ports.UserRoleRepository, roleRepo ports.RoleRepository, groupRepo ports.GroupRepository, mappingRepo ports.ClaimMappingRepository, logger ...ports.Logger) *UserService {
+	return &UserService{
+		userRepo:  userRepo,
+		roleRepo:  roleRepo,
+		groupRepo: groupRepo,
+		authz:     NewAuthorizationService(userRepo, roleRepo, groupRepo, mappingRepo, logger...),
+		logger:    resolveLogger(logger),
+	}
 }
 
-func (s *UserService) AssignRole(ctx context.Context, appID, userID, roleID string) error {
+// Assigning a role the user already holds in the same scope replaces its
+// window.
+func (s *UserService) AssignRole(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	roleID := grant.RoleID
 	if appID == "" || userID == "" || roleID == "" {
 		s.logger.Warn(ctx, "invalid assign role input", "app_id", appID, "user_id", userID, "role_id", roleID)
 		return domain.ErrInvalidInput
 	}
+	if err := errors.Join(domain.ValidateID(userID), domain.ValidateID(roleID)); err != nil {
+		s.logger.Warn(ctx, "invalid assign role id", "app_id", appID, "user_id", userID, "role_id", roleID)
+		return err
+	}
+	if grant.Scope != "" {
+		if err := domain.ValidateScope(grant.Scope); err != nil {
+			s.logger.Warn(ctx, "invalid assignment scope", "app_id", appID, "user_id", userID, "role_id", roleID, "scope", grant.Scope)
+			return err
+		}
+	}
+	if grant.ExpiresAt != nil {
+		if !grant.ExpiresAt.After(time.Now()) {
+			s.logger.Warn(ctx, "assignment already expired", "app_id", appID, "user_id", userID, "role_id", roleID)
+			return fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidInput)
+		}
+		if grant.StartsAt != nil && !grant.ExpiresAt.After(*grant.StartsAt) {
+			s.logger.Warn(ctx, "assignment window is empty", "app_id", appID, "user_id", userID, "role_id", roleID)
+			return fmt.Errorf("%w: expires_at must be after starts_at", domain.ErrInvalidInput)
+		}
+	}
 	roles, err := s.roleRepo.ListByAppID(ctx, appID)
 	if err != nil {
 		s.logger.Error(ctx, "failed to list roles for assignment", "app_id", appID, "error", err)
@@ -202,11 +716,54 @@ func (s *UserService) AssignRole(ctx context.Context, appID, userID, roleID stri
 		s.logger.Warn(ctx, "role not found for assignment", "app_id", appID, "user_id", userID, "role_id", roleID)
 		return domain.ErrNotFound
 	}
-	if err := s.userRepo.AssignRole(ctx, appID, userID, roleID); err != nil {
+	if err := s.userRepo.AssignRole(ctx, appID, userID, grant); err != nil {
 		s.logger.Error(ctx, "failed to assign role", "app_id", appID, "user_id", userID, "role_id", roleID, "error", err)
 		return err
 	}
-	s.logger.Info(ctx, "role assigned", "app_id", appID, "user_id", userID, "role_id", roleID)
+	s.logger.Info(ctx, "role assigned", "app_id", appID, "user_id", userID, "role_id", roleID, "scope", grant.Scope)
+	return nil
+}
+
+func (s *UserService) RevokeRole(ctx context.Context, appID, userID, roleID string) error {
+	if appID == "" || userID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid revoke role input", "app_id", appID, "user_id", userID, "role_id", roleID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.userRepo.RevokeRole(ctx, appID, userID, roleID); err != nil {
+		s.logger.Error(ctx, "failed to revoke role", "app_id", appID, "user_id", userID, "role_id", roleID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "role revoked", "app_id", appID, "user_id", userID, "role_id", roleID)
+	return nil
+}
+
+func (s *UserService) RevokeGrant(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	if appID == "" || userID == "" || grant.RoleID == "" {
+		s.logger.Warn(ctx, "invalid revoke grant input", "app_id", appID, "user_id", userID, "role_id", grant.RoleID)
+		return domain.ErrInvalidInput
+	}
+	if err := domain.ValidateScope(grant.Scope); err != nil {
+		s.logger.Warn(ctx, "invalid revoke grant scope", "app_id", appID, "user_id", userID, "role_id", grant.RoleID, "scope", grant.Scope)
+		return err
+	}
+	if err := s.userRepo.RevokeGrant(ctx, appID, userID, grant); err != nil {
+		s.logger.Error(ctx, "failed to revoke grant", "app_id", appID, "user_id", userID, "role_id", grant.RoleID, "scope", grant.Scope, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "grant revoked", "app_id", appID, "user_id", userID, "role_id", grant.RoleID, "scope", grant.Scope)
+	return nil
+}
+
+func (s *UserService) RevokeAllRoles(ctx context.Context, appID, userID string) error {
+	if appID == "" || userID == "" {
+		s.logger.Warn(ctx, "invalid revoke all roles input", "app_id", appID, "user_id", userID)
+		return domain.ErrInvalidInput
+	}
+	if err := s.userRepo.RevokeAllRoles(ctx, appID, userID); err != nil {
+		s.logger.Error(ctx, "failed to revoke all roles", "app_id", appID, "user_id", userID, "error", err)
+		return err
+	}
+	s.logger.Info(ctx, "all roles revoked", "app_id", appID, "user_id", userID)
 	return nil
 }
 
@@ -224,49 +781,590 @@ func (s *UserService) GetUserAppRoles(ctx context.Context, appID, userID string)
 	return userRoles, nil
 }
 
+func (s *UserService) ListAppUsers(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid app users query", "app_id", appID)
+		return domain.Page[domain.UserAppRoles]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid app users page", "app_id", appID, "limit", page.Limit)
+		return domain.Page[domain.UserAppRoles]{}, err
+	}
+	users, err := s.userRepo.ListByAppID(ctx, appID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list app users", "app_id", appID, "error", err)
+		return domain.Page[domain.UserAppRoles]{}, err
+	}
+	s.logger.Debug(ctx, "app users listed", "app_id", appID, "count", len(users.Items))
+	return users, nil
+}
+
+func (s *UserService) ListRoleMembers(ctx context.Context, appID, roleID string, page domain.PageRequest) (domain.Page[domain.RoleMember], error) {
+	if appID == "" || roleID == "" {
+		s.logger.Warn(ctx, "invalid role members query", "app_id", appID, "role_id", roleID)
+		return domain.Page[domain.RoleMember]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid role members page", "app_id", appID, "role_id", roleID, "limit", page.Limit)
+		return domain.Page[domain.RoleMember]{}, err
+	}
+	members, err := s.userRepo.ListByRole(ctx, appID, roleID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list role members", "app_id", appID, "role_id", roleID, "error", err)
+		return domain.Page[domain.RoleMember]{}, err
+	}
+	// Member items of expired grants linger until DynamoDB TTL removes them.
+	now := time.Now()
+	members.Items = slices.DeleteFunc(members.Items, func(member domain.RoleMember) bool {
+		return domain.RoleGrant{ExpiresAt: member.ExpiresAt}.ExpiredAt(now)
+	})
+	s.logger.Debug(ctx, "role members listed", "app_id", appID, "role_id", roleID, "count", len(members.Items))
+	return members, nil
+}
+
+func (s *UserService) ListUserApplications(ctx context.Context, userID string, includePermissions bool, page domain.PageRequest) (domain.Page[domain.UserApplicationAccess], error) {
+	if userID == "" {
+		s.logger.Warn(ctx, "invalid user applications query", "user_id", userID)
+		return domain.Page[domain.UserApplicationAccess]{}, domain.ErrInvalidInput
+	}
+	page, err := normalizePage(page)
+	if err != nil {
+		s.logger.Warn(ctx, "invalid user applications page", "user_id", userID, "limit", page.Limit)
+		return domain.Page[domain.UserApplicationAccess]{}, err
+	}
+	assigned, err := s.userRepo.ListByUser(ctx, userID, page)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list user applications", "user_id", userID, "error", err)
+		return domain.Page[domain.UserApplicationAccess]{}, err
+	}
+	out := domain.Page[domain.UserApplicationAccess]{Items: make([]domain.UserApplicationAccess, 0, len(assigned.Items)), NextToken: assigned.NextToken}
+	now := time.Now()
+	data := newAuthorizationData(s.authz)
+	for _, userRoles := range assigned.Items {
+		held := userRoles.HeldRoles(now)
+		if len(held) == 0 && len(userRoles.Groups) == 0 {
+			continue
+		}
+		access := domain.UserApplicationAccess{AppID: userRoles.AppID, Roles: held, UpdatedAt: userRoles.UpdatedAt}
+		if includePermissions {
+			data.users[[2]string{userRoles.AppID, userID}] = userRolesResult{userRoles: userRoles}
+			effective, err := s.authz.effectivePermissions(ctx, data, domain.AccessRequest{AppID: userRoles.AppID, UserID: userID}, false)
+			if err != nil {
+				s.logger.Error(ctx, "failed to resolve permissions for user applications", "app_id", userRoles.AppID, "user_id", userID, "error", err)
+				return domain.Page[domain.UserApplicationAccess]{}, err
+			}
+			access.Permissions = effective.Permissions
+		}
+		out.Items = append(out.Items, access)
+	}
+	s.logger.Debug(ctx, "user applications listed", "user_id", userID, "count", len(out.Items))
+	return out, nil
+}
+
+func (s *UserService) PruneExpired(ctx context.Context, appID string) (int, error) {
+	if appID == "" {
+		s.logger.Warn(ctx, "invalid prune expired input", "app_id", appID)
+		return 0, domain.ErrInvalidInput
+	}
+	now := time.Now()
+	pruned := 0
+	page := domain.PageRequest{Limit: domain.MaxPageLimit}
+	for {
+		assigned, err := s.userRepo.ListByAppID(ctx, appID, page)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list assignments for pruning", "app_id", appID, "error", err)
+			return pruned, err
+		}
+		for _, userRoles := range assigned.Items {
+			if !slices.ContainsFunc(userRoles.Grants, func(grant domain.RoleGrant) bool { return grant.ExpiredAt(now) }) {
+				continue
+			}
+			err := s.userRepo.PruneExpired(ctx, appID, userRoles.UserID)
+			if errors.Is(err, domain.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				s.logger.Error(ctx, "failed to prune expired grants", "app_id", appID, "user_id", userRoles.UserID, "error", err)
+				return pruned, err
+			}
+			pruned++
+		}
+		if assigned.NextToken == "" {
+			break
+		}
+		page.NextToken = assigned.NextToken
+	}
+	s.logger.Info(ctx, "expired grants pruned", "app_id", appID, "assignments", pruned)
+	return pruned, nil
+}
+
 type AuthorizationService struct {
-	userRepo ports.UserRoleRepository
-	roleRepo ports.RoleRepository
-	logger   ports.Logger
+	userRepo    ports.UserRoleRepository
+	roleRepo    ports.RoleRepository
+	groupRepo   ports.GroupRepository
+	mappingRepo ports.ClaimMappingRepository
+	conditions  conditionCache
+	logger      ports.Logger
 }
 
-func NewAuthorizationService(userRepo ports.UserRoleRepository, roleRepo ports.RoleRepository, logger ...ports.Logger) *AuthorizationService {
-	return &AuthorizationService{userRepo: userRepo, roleRepo: roleRepo, logger: resolveLogger(logger)}
+func NewAuthorizationService(userRepo ports.UserRoleRepository, roleRepo ports.RoleRepository, groupRepo ports.GroupRepository, mappingRepo ports.ClaimMappingRepository, logger ...ports.Logger) *AuthorizationService {
+	return &AuthorizationService{userRepo: userRepo, roleRepo: roleRepo, groupRepo: groupRepo, mappingRepo: mappingRepo, logger: resolveLogger(logger)}
 }
 
 func (s *AuthorizationService) IsAllowed(ctx context.Context, appID, userID, permission string) (bool, error) {
+	decision, err := s.Decide(ctx, domain.AccessRequest{AppID: appID, UserID: userID, Permission: permission})
+	return decision.Allowed, err
+}
+
+func (s *AuthorizationService) Decide(ctx context.Context, req domain.AccessRequest) (domain.Decision, error) {
+	if err := s.checkRequest(ctx, req); err != nil {
+		return domain.Decision{}, err
+	}
+	return s.decide(ctx, newAuthorizationData(s), req)
+}
+
+// DecideBatch reads each user's assignment and each app's roles once.
+func (s *AuthorizationService) DecideBatch(ctx context.Context, reqs []domain.AccessRequest) ([]domain.Decision, error) {
+	if len(reqs) == 0 || len(reqs) > domain.MaxAuthorizeBatch {
+		s.logger.Warn(ctx, "invalid authorize batch size", "count", len(reqs))
+		return nil, fmt.Errorf("%w: a batch holds 1 to %d checks", domain.ErrInvalidInput, domain.MaxAuthorizeBatch)
+	}
+	for i, req := range reqs {
+		if err := s.checkRequest(ctx, req); err != nil {
+			return nil, fmt.Errorf("check %d: %w", i, err)
+		}
+	}
+	data := newAuthorizationData(s)
+	decisions := make([]domain.Decision, 0, len(reqs))
+	for _, req := range reqs {
+		decision, err := s.decide(ctx, data, req)
+		if err != nil {
+			return nil, err
+		}
+		decisions = append(decisions, decision)
+	}
+	s.logger.Debug(ctx, "authorize batch decided", "count", len(reqs), "users", len(data.users), "apps", len(data.roles))
+	return decisions, nil
+}
+
+func (s *AuthorizationService) Explain(ctx context.Context, req domain.AccessRequest) (domain.Explanation, error) {
+	if err := s.checkRequest(ctx, req); err != nil {
+		return domain.Explanation{}, err
+	}
+	appID, userID := req.AppID, req.UserID
+	explanation := domain.Explanation{AssignedRoles: []string{}, ActiveRoles: []string{}, EvaluatedRoles: []string{}}
+	data := newAuthorizationData(s)
+	now := time.Now()
+	sub, err := s.resolve(ctx, data, req, now, false)
+	if err != nil {
+		s.logger.Error(ctx, "failed to resolve roles for explanation", "app_id", appID, "user_id", userID, "error", err)
+		return domain.Explanation{}, err
+	}
+	if !sub.assigned && len(sub.mapped) == 0 {
+		explanation.Reason = domain.ReasonNoAssignment
+		s.logger.Info(ctx, "authorization explained", "app_id", appID, "user_id", userID, "permission", req.Permission, "reason", explanation.Reason)
+		return explanation, nil
+	}
+	userRoles, mapped, active := sub.userRoles, sub.mapped, sub.active
+	switch {
+	case len(userRoles.Roles) == 0 && len(userRoles.Groups) == 0 && len(mapped) == 0:
+		explanation.Reason = domain.ReasonEmptyRoles
+	case len(active) == 0:
+		explanation.Reason = domain.ReasonNoActiveGrants
+	default:
+		idx, err := data.roleIndex(ctx, appID)
+		if err != nil {
+			s.logger.Error(ctx, "failed to list roles for explanation", "app_id", appID, "error", err)
+			return domain.Explanation{}, err
+		}
+		explanation = idx.explain(active, req.Permission, func(expr string) bool {
+			return s.conditions.holds(expr, req.Context)
+		})
+		if explanation.EvaluatedRoles == nil {
+			explanation.EvaluatedRoles = []string{}
+		}
+	}
+	explanation.AssignedRoles = append([]string{}, userRoles.Roles...)
+	explanation.Groups = userRoles.Groups
+	explanation.MappedRoles = mapped
+	explanation.ActiveRoles = active
+	explanation.InactiveGrants = userRoles.InactiveGrants(now, req.Resource)
+	s.logger.Info(ctx, "authorization explained", "app_id", appID, "user_id", userID, "permission", req.Permission, "reason", explanation.Reason)
+	return explanation, nil
+}
+
+// EffectivePermissions leaves out grants that a deny rule fully covers.
+func (s *AuthorizationService) EffectivePermissions(ctx context.Context, req domain.AccessRequest, provenance bool) (domain.EffectivePermissions, error) {
+	appID, userID, resource := req.AppID, req.UserID, req.Resource
+	if appID == "" || userID == "" {
+		s.logger.Warn(ctx, "invalid effective permissions query", "app_id", appID, "user_id", userID)
+		return domain.EffectivePermissions{}, domain.ErrInvalidInput
+	}
+	if resource != "" {
+		if err := domain.ValidateScope(resource); err != nil {
+			s.logger.Warn(ctx, "invalid effective permissions resource", "app_id", appID, "user_id", userID, "resource", resource)
+			return domain.EffectivePermissions{}, err
+		}
+	}
+	out, err := s.effectivePermissions(ctx, newAuthorizationData(s), req, provenance)
+	if err != nil {
+		s.logger.Error(ctx, "failed to resolve effective permissions", "app_id", appID, "user_id", userID, "error", err)
+		return domain.EffectivePermissions{}, err
+	}
+	s.logger.Debug(ctx, "effective permissions resolved", "app_id", appID, "user_id", userID, "count", len(out.Permissions))
+	return out, nil
+}
+
+func (s *AuthorizationService) effectivePermissions(ctx context.Context, data *authorizationData, req domain.AccessRequest, provenance bool) (domain.EffectivePermissions, error) {
+	out := domain.EffectivePermissions{AppID: req.AppID, UserID: req.UserID, Resource: req.Resource, Roles: []string{}, Permissions: []string{}}
+	sub, err := s.resolve(ctx, data, req, time.Now(), false)
+	if err != nil || len(sub.active) == 0 {
+		return out, err
+	}
+	idx, err := data.roleIndex(ctx, req.AppID)
+	if err != nil {
+		return domain.EffectivePermissions{}, err
+	}
+	out.Roles = idx.expand(sub.active)
+	if out.Roles == nil {
+		out.Roles = []string{}
+	}
+	rules := idx.denyRules(sub.active)
+	grants := slices.DeleteFunc(idx.grants(sub.active), func(grant domain.PermissionGrant) bool {
+		return slices.ContainsFunc(rules, func(rule domain.DenyRule) bool {
+			return domain.MatchPermission(rule.Pattern, grant.Pattern)
+		})
+	})
+	for _, grant := range grants {
+		if !slices.Contains(out.Permissions, grant.Pattern) {
+			out.Permissions = append(out.Permissions, grant.Pattern)
+		}
+	}
+	// A pattern is conditional only if no role grants it unconditionally.
+	for _, pattern := range out.Permissions {
+		unconditional := slices.ContainsFunc(grants, func(grant domain.PermissionGrant) bool {
+			return grant.Pattern == pattern && grant.Condition == ""
+		})
+		if !unconditional {
+			out.Conditional = append(out.Conditional, pattern)
+		}
+	}
+	for _, rule := range rules {
+		if !slices.Contains(out.Deny, rule.Pattern) {
+			out.Deny = append(out.Deny, rule.Pattern)
+		}
+	}
+	if provenance {
+		out.Grants = grants
+		out.DenyRules = rules
+	}
+	return out, nil
+}
+
+func (s *AuthorizationService) checkRequest(ctx context.Context, req domain.AccessRequest) error {
+	appID, userID, permission := req.AppID, req.UserID, req.Permission
 	if appID == "" || userID == "" || permission == "" {
 		s.logger.Warn(ctx, "invalid authorize input", "app_id", appID, "user_id", userID, "permission", permission)
-		return false, domain.ErrInvalidInput
+		return domain.ErrInvalidInput
 	}
-	userRoles, err := s.userRepo.GetByUserAndApp(ctx, appID, userID)
-	if err != nil {
-		if errors.Is(err, domain.ErrNotFound) {
-			s.logger.Info(ctx, "user has no roles", "app_id", appID, "user_id", userID)
-			return false, nil
+	if err := domain.ValidatePermission(permission); err != nil {
+		s.logger.Warn(ctx, "invalid authorize permission", "app_id", appID, "user_id", userID, "permission", permission)
+		return err
+	}
+	if req.Resource != "" {
+		if err := domain.ValidateScope(req.Resource); err != nil {
+			s.logger.Warn(ctx, "invalid authorize resource", "app_id", appID, "user_id", userID, "resource", req.Resource)
+			return err
 		}
-		s.logger.Error(ctx, "failed to get user roles for authorization", "app_id", appID, "user_id", userID, "error", err)
-		return false, err
 	}
-	if len(userRoles.Roles) == 0 {
-		s.logger.Info(ctx, "authorization denied: empty roles", "app_id", appID, "user_id", userID)
-		return false, nil
+	return nil
+}
+
+func (s *AuthorizationService) decide(ctx context.Context, data *authorizationData, req domain.AccessRequest) (domain.Decision, error) {
+	appID, userID, permission := req.AppID, req.UserID, req.Permission
+	sub, err := s.resolve(ctx, data, req, time.Now(), true)
+	if err != nil {
+		s.logger.Error(ctx, "failed to resolve roles for authorization", "app_id", appID, "user_id", userID, "error", err)
+		return domain.Decision{}, err
 	}
-	roles, err := s.roleRepo.ListByAppID(ctx, appID)
+	if !sub.assigned && len(sub.mapped) == 0 {
+		s.logger.Info(ctx, "user has no roles", "app_id", appID, "user_id", userID)
+		return domain.Decision{}, nil
+	}
+	active := sub.active
+	if len(active) == 0 {
+		s.logger.Info(ctx, "authorization denied: no active roles", "app_id", appID, "user_id", userID, "resource", req.Resource)
+		return domain.Decision{}, nil
+	}
+	idx, err := data.roleIndex(ctx, appID)
 	if err != nil {
 		s.logger.Error(ctx, "failed to list roles for authorization", "app_id", appID, "error", err)
-		return false, err
+		return domain.Decision{}, err
 	}
-	rolePerms := map[string][]string{}
-	for _, role := range roles {
-		rolePerms[role.ID] = role.Permissions
+	decision := idx.decide(active, permission, func(expr string) bool {
+		return s.conditions.holds(expr, req.Context)
+	})
+	switch {
+	case decision.Allowed:
+		s.logger.Info(ctx, "authorization allowed", "app_id", appID, "user_id", userID, "permission", permission)
+	case decision.DeniedBy != nil:
+		s.logger.Info(ctx, "authorization denied by rule", "app_id", appID, "user_id", userID, "permission", permission,
+			"role_id", decision.DeniedBy.RoleID, "pattern", decision.DeniedBy.Pattern)
+	default:
+		s.logger.Info(ctx, "authorization denied", "app_id", appID, "user_id", userID, "permission", permission)
+	}
+	return decision, nil
+}
+
+type subject struct {
+	userRoles domain.UserAppRoles
+	assigned  bool
+	mapped    []string
+	active    []string
+}
+
+// resolve is shared by every check so that they cannot disagree on what a
+// user holds.
+func (s *AuthorizationService) resolve(ctx context.Context, data *authorizationData, req domain.AccessRequest, now time.Time, sync bool) (subject, error) {
+	var sub subject
+	var err error
+	sub.userRoles, sub.assigned, err = data.assignment(ctx, req.AppID, req.UserID)
+	if err != nil {
+		return subject{}, err
+	}
+	sub.userRoles, err = s.dropStaleSynced(ctx, data, req, sub.userRoles, sync)
+	if err != nil {
+		return subject{}, err
+	}
+	sub.mapped, err = s.mappedRoles(ctx, data, req, sub.userRoles, sync)
+	if err != nil {
+		return subject{}, err
+	}
+	if !sub.assigned && len(sub.mapped) == 0 {
+		return sub, nil
+	}
+	active, err := data.activeRoles(ctx, sub.userRoles, now, req.Resource)
+	if err != nil {
+		return subject{}, err
+	}
+	sub.active = withRoles(active, sub.mapped)
+	return sub, nil
+}
+
+// A failed sync is logged and does not change the decision.
+func (s *AuthorizationService) mappedRoles(ctx context.Context, data *authorizationData, req domain.AccessRequest, userRoles domain.UserAppRoles, sync bool) ([]string, error) {
+	if len(req.Claims) == 0 {
+		return nil, nil
+	}
+	mappings, err := data.claimMappings(ctx, req.AppID)
+	if err != nil {
+		return nil, err
+	}
+	var mapped []string
+	for _, mapping := range mappings {
+		if !mapping.Matches(req.Claims) {
+			continue
+		}
+		if !slices.Contains(mapped, mapping.RoleID) {
+			mapped = append(mapped, mapping.RoleID)
+		}
+		if sync && mapping.Sync && !userRoles.HasAppWideGrant(mapping.RoleID) {
+			s.syncMappedRole(ctx, data, req.AppID, req.UserID, mapping)
+		}
+	}
+	return mapped, nil
+}
+
+// dropStaleSynced leaves out synced grants whose mapping was deleted, changed
+// role or stopped syncing, or no longer matches the caller's claims, and
+// revokes them when sync is set. Without claims a grant whose mapping still
+// syncs its role is kept.
+func (s *AuthorizationService) dropStaleSynced(ctx context.Context, data *authorizationData, req domain.AccessRequest, userRoles domain.UserAppRoles, sync bool) (domain.UserAppRoles, error) {
+	if !slices.ContainsFunc(userRoles.Grants, func(grant domain.RoleGrant) bool { return grant.Source != "" }) {
+		return userRoles, nil
+	}
+	mappings, err := data.claimMappings(ctx, req.AppID)
+	if err != nil {
+		return domain.UserAppRoles{}, err
+	}
+	var stale []domain.RoleGrant
+	kept := userRoles.WithoutGrants(func(grant domain.RoleGrant) bool {
+		mappingID, ok := grant.SyncedBy()
+		if !ok {
+			return false
+		}
+		i := slices.IndexFunc(mappings, func(mapping domain.ClaimMapping) bool { return mapping.ID == mappingID })
+		if i >= 0 && mappings[i].Sync && mappings[i].RoleID == grant.RoleID && (len(req.Claims) == 0 || mappings[i].Matches(req.Claims)) {
+			return false
+		}
+		stale = append(stale, grant)
+		return true
+	})
+	if len(stale) == 0 {
+		return userRoles, nil
 	}
-	for _, userRole := range userRoles.Roles {
-		if perms, ok := rolePerms[userRole]; ok && slices.Contains(perms, permission) {
-			s.logger.Info(ctx, "authorization allowed", "app_id", appID, "user_id", userID, "permission", permission)
-			return true, nil
+	data.users[[2]string{req.AppID, req.UserID}] = userRolesResult{userRoles: kept}
+	if sync {
+		for _, grant := range stale {
+			s.revokeSynced(ctx, req.AppID, req.UserID, grant)
+		}
+	}
+	return kept, nil
+}
+
+// A failed revoke is logged; the grant is dropped again on the next check.
+func (s *AuthorizationService) revokeSynced(ctx context.Context, appID, userID string, grant domain.RoleGrant) {
+	err := s.userRepo.RevokeSynced(ctx, appID, userID, grant)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		s.logger.Error(ctx, "failed to revoke claim-mapped role", "app_id", appID, "user_id", userID, "role_id", grant.RoleID, "source", grant.Source, "error", err)
+		return
+	}
+	s.logger.Info(ctx, "claim-mapped role revoked", "app_id", appID, "user_id", userID, "role_id", grant.RoleID, "source", grant.Source)
+}
+
+func (s *AuthorizationService) syncMappedRole(ctx context.Context, data *authorizationData, appID, userID string, mapping domain.ClaimMapping) {
+	roleID := mapping.RoleID
+	key := [3]string{appID, userID, roleID}
+	if data.synced[key] {
+		return
+	}
+	data.synced[key] = true
+	idx, err := data.roleIndex(ctx, appID)
+	if err != nil {
+		s.logger.Error(ctx, "failed to list roles for claim mapping sync", "app_id", appID, "error", err)
+		return
+	}
+	if _, ok := idx[roleID]; !ok {
+		s.logger.Warn(ctx, "claim mapping names unknown role", "app_id", appID, "user_id", userID, "role_id", roleID)
+		return
+	}
+	if err := domain.ValidateID(userID); err != nil {
+		s.logger.Warn(ctx, "claim-mapped role not synced for user id", "app_id", appID, "user_id", userID, "role_id", roleID)
+		return
+	}
+	grant := domain.RoleGrant{RoleID: roleID, Source: domain.ClaimMappingSource(mapping.ID)}
+	if err := s.userRepo.AssignRole(ctx, appID, userID, grant); err != nil {
+		s.logger.Error(ctx, "failed to sync claim-mapped role", "app_id", appID, "user_id", userID, "role_id", roleID, "error", err)
+		return
+	}
+	s.logger.Info(ctx, "claim-mapped role synced", "app_id", appID, "user_id", userID, "role_id", roleID)
+}
+
+// authorizationData memoizes reads, failed ones included, across a set of
+// decisions.
+type authorizationData struct {
+	s        *AuthorizationService
+	users    map[[2]string]userRolesResult
+	roles    map[string]roleIndexResult
+	groups   map[[2]string]*domain.Group
+	mappings map[string]claimMappingsResult
+	synced   map[[3]string]bool
+}
+
+type claimMappingsResult struct {
+	mappings []domain.ClaimMapping
+	err      error
+}
+
+type userRolesResult struct {
+	userRoles domain.UserAppRoles
+	err       error
+}
+
+type roleIndexResult struct {
+	idx roleIndex
+	err error
+}
+
+func newAuthorizationData(s *AuthorizationService) *authorizationData {
+	return &authorizationData{
+		s:        s,
+		users:    map[[2]string]userRolesResult{},
+		roles:    map[string]roleIndexResult{},
+		groups:   map[[2]string]*domain.Group{},
+		mappings: map[string]claimMappingsResult{},
+		synced:   map[[3]string]bool{},
+	}
+}
+
+func (d *authorizationData) userRoles(ctx context.Context, appID, userID string) (domain.UserAppRoles, error) {
+	key := [2]string{appID, userID}
+	result, ok := d.users[key]
+	if !ok {
+		result.userRoles, result.err = d.s.userRepo.GetByUserAndApp(ctx, appID, userID)
+		d.users[key] = result
+	}
+	return result.userRoles, result.err
+}
+
+func (d *authorizationData) assignment(ctx context.Context, appID, userID string) (domain.UserAppRoles, bool, error) {
+	userRoles, err := d.userRoles(ctx, appID, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.UserAppRoles{AppID: appID, UserID: userID}, false, nil
+	}
+	return userRoles, err == nil, err
+}
+
+func (d *authorizationData) claimMappings(ctx context.Context, appID string) ([]domain.ClaimMapping, error) {
+	result, ok := d.mappings[appID]
+	if !ok {
+		result.mappings, result.err = d.s.mappingRepo.ListByAppID(ctx, appID)
+		d.mappings[appID] = result
+	}
+	return result.mappings, result.err
+}
+
+func (d *authorizationData) roleIndex(ctx context.Context, appID string) (roleIndex, error) {
+	result, ok := d.roles[appID]
+	if !ok {
+		var roles []domain.Role
+		roles, result.err = d.s.roleRepo.ListByAppID(ctx, appID)
+		result.idx = indexRoles(roles)
+		d.roles[appID] = result
+	}
+	return result.idx, result.err
+}
+
+func (d *authorizationData) activeRoles(ctx context.Context, userRoles domain.UserAppRoles, t time.Time, resource string) ([]string, error) {
+	active := userRoles.ActiveRoles(t, resource)
+	if len(userRoles.Groups) == 0 {
+		return active, nil
+	}
+	var missing []string
+	for _, groupID := range userRoles.Groups {
+		if _, ok := d.groups[[2]string{userRoles.AppID, groupID}]; !ok {
+			missing = append(missing, groupID)
+		}
+	}
+	if len(missing) > 0 {
+		found, err := d.s.groupRepo.GetMany(ctx, userRoles.AppID, missing)
+		if err != nil {
+			return nil, err
+		}
+		for _, groupID := range missing {
+			d.groups[[2]string{userRoles.AppID, groupID}] = nil
+		}
+		for i := range found {
+			d.groups[[2]string{userRoles.AppID, found[i].ID}] = &found[i]
+		}
+	}
+	var groupRoles []string
+	for _, groupID := range userRoles.Groups {
+		if group := d.groups[[2]string{userRoles.AppID, groupID}]; group != nil {
+			groupRoles = append(groupRoles, group.Roles...)
+		}
+	}
+	return withRoles(active, groupRoles), nil
+}
+
+func withRoles(active, roleIDs []string) []string {
+	out := slices.Clone(active)
+	for _, roleID := range roleIDs {
+		if !slices.Contains(out, roleID) {
+			out = append(out, roleID)
 		}
 	}
-	s.logger.Info(ctx, "authorization denied", "app_id", appID, "user_id", userID, "permission", permission)
-	return false, nil
+	return out
 }
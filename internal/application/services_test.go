@@ -3,7 +3,9 @@ package application
 import (
 	"context"
 	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -28,6 +30,16 @@ func (m *appRepoMock) GetByID(ctx context.Context, appID string) (domain.Applica
 	return args.Get(0).(domain.Application), args.Error(1)
 }
 
+func (m *appRepoMock) List(ctx context.Context, query domain.ApplicationQuery) (domain.Page[domain.Application], error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(domain.Page[domain.Application]), args.Error(1)
+}
+
+func (m *appRepoMock) Delete(ctx context.Context, appID, nextToken string) (domain.ApplicationDeletion, error) {
+	args := m.Called(ctx, appID, nextToken)
+	return args.Get(0).(domain.ApplicationDeletion), args.Error(1)
+}
+
 type roleRepoMock struct{ mock.Mock }
 
 func (m *roleRepoMock) Create(ctx context.Context, role domain.Role) error {
@@ -35,8 +47,13 @@ func (m *roleRepoMock) Create(ctx context.Context, role domain.Role) error {
 	return args.Error(0)
 }
 
-func (m *roleRepoMock) Update(ctx context.Context, role domain.Role) error {
-	args := m.Called(ctx, role)
+func (m *roleRepoMock) Update(ctx context.Context, role domain.Role, unchanged []domain.Role) error {
+	args := m.Called(ctx, role, unchanged)
+	return args.Error(0)
+}
+
+func (m *roleRepoMock) Delete(ctx context.Context, appID, roleID string) error {
+	args := m.Called(ctx, appID, roleID)
 	return args.Error(0)
 }
 
@@ -45,6 +62,11 @@ func (m *roleRepoMock) ListByAppID(ctx context.Context, appID string) ([]domain.
 	return args.Get(0).([]domain.Role), args.Error(1)
 }
 
+func (m *roleRepoMock) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Role], error) {
+	args := m.Called(ctx, appID, page)
+	return args.Get(0).(domain.Page[domain.Role]), args.Error(1)
+}
+
 type permissionRepoMock struct{ mock.Mock }
 
 func (m *permissionRepoMock) Create(ctx context.Context, permission domain.Permission) error {
@@ -52,39 +74,207 @@ func (m *permissionRepoMock) Create(ctx context.Context, permission domain.Permi
 	return args.Error(0)
 }
 
+func (m *permissionRepoMock) Update(ctx context.Context, permission domain.Permission) error {
+	args := m.Called(ctx, permission)
+	return args.Error(0)
+}
+
+func (m *permissionRepoMock) Delete(ctx context.Context, appID, permissionID string) error {
+	args := m.Called(ctx, appID, permissionID)
+	return args.Error(0)
+}
+
 func (m *permissionRepoMock) ListByAppID(ctx context.Context, appID string) ([]domain.Permission, error) {
 	args := m.Called(ctx, appID)
 	return args.Get(0).([]domain.Permission), args.Error(1)
 }
 
+func (m *permissionRepoMock) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Permission], error) {
+	args := m.Called(ctx, appID, page)
+	return args.Get(0).(domain.Page[domain.Permission]), args.Error(1)
+}
+
 type userRoleRepoMock struct{ mock.Mock }
 
-func (m *userRoleRepoMock) AssignRole(ctx context.Context, appID, userID, roleID string) error {
+func (m *userRoleRepoMock) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error) {
+	args := m.Called(ctx, appID, page)
+	return args.Get(0).(domain.Page[domain.UserAppRoles]), args.Error(1)
+}
+
+func (m *userRoleRepoMock) AssignRole(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	args := m.Called(ctx, appID, userID, grant)
+	return args.Error(0)
+}
+
+func (m *userRoleRepoMock) PruneExpired(ctx context.Context, appID, userID string) error {
+	args := m.Called(ctx, appID, userID)
+	return args.Error(0)
+}
+
+func (m *userRoleRepoMock) ListByUser(ctx context.Context, userID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error) {
+	args := m.Called(ctx, userID, page)
+	return args.Get(0).(domain.Page[domain.UserAppRoles]), args.Error(1)
+}
+
+func (m *userRoleRepoMock) ListByRole(ctx context.Context, appID, roleID string, page domain.PageRequest) (domain.Page[domain.RoleMember], error) {
+	args := m.Called(ctx, appID, roleID, page)
+	return args.Get(0).(domain.Page[domain.RoleMember]), args.Error(1)
+}
+
+func (m *userRoleRepoMock) RevokeRole(ctx context.Context, appID, userID, roleID string) error {
 	args := m.Called(ctx, appID, userID, roleID)
 	return args.Error(0)
 }
 
+func (m *userRoleRepoMock) RevokeGrant(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	args := m.Called(ctx, appID, userID, grant)
+	return args.Error(0)
+}
+
+func (m *userRoleRepoMock) RevokeSynced(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	args := m.Called(ctx, appID, userID, grant)
+	return args.Error(0)
+}
+
+func (m *userRoleRepoMock) RevokeAllRoles(ctx context.Context, appID, userID string) error {
+	args := m.Called(ctx, appID, userID)
+	return args.Error(0)
+}
+
 func (m *userRoleRepoMock) GetByUserAndApp(ctx context.Context, appID, userID string) (domain.UserAppRoles, error) {
 	args := m.Called(ctx, appID, userID)
 	return args.Get(0).(domain.UserAppRoles), args.Error(1)
 }
 
+type groupRepoMock struct{ mock.Mock }
+
+func (m *groupRepoMock) Create(ctx context.Context, group domain.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) Update(ctx context.Context, group domain.Group) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) Delete(ctx context.Context, appID, groupID string) error {
+	args := m.Called(ctx, appID, groupID)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) Get(ctx context.Context, appID, groupID string) (domain.Group, error) {
+	args := m.Called(ctx, appID, groupID)
+	return args.Get(0).(domain.Group), args.Error(1)
+}
+
+func (m *groupRepoMock) GetMany(ctx context.Context, appID string, groupIDs []string) ([]domain.Group, error) {
+	args := m.Called(ctx, appID, groupIDs)
+	return args.Get(0).([]domain.Group), args.Error(1)
+}
+
+func (m *groupRepoMock) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Group], error) {
+	args := m.Called(ctx, appID, page)
+	return args.Get(0).(domain.Page[domain.Group]), args.Error(1)
+}
+
+func (m *groupRepoMock) AssignRole(ctx context.Context, appID, groupID, roleID string) error {
+	args := m.Called(ctx, appID, groupID, roleID)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) RevokeRole(ctx context.Context, appID, groupID, roleID string) error {
+	args := m.Called(ctx, appID, groupID, roleID)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) AddMember(ctx context.Context, appID, groupID, userID string) error {
+	args := m.Called(ctx, appID, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) RemoveMember(ctx context.Context, appID, groupID, userID string) error {
+	args := m.Called(ctx, appID, groupID, userID)
+	return args.Error(0)
+}
+
+func (m *groupRepoMock) ListMembers(ctx context.Context, appID, groupID string, page domain.PageRequest) (domain.Page[domain.GroupMember], error) {
+	args := m.Called(ctx, appID, groupID, page)
+	return args.Get(0).(domain.Page[domain.GroupMember]), args.Error(1)
+}
+
+type claimMappingRepoMock struct{ mock.Mock }
+
+func (m *claimMappingRepoMock) Create(ctx context.Context, mapping domain.ClaimMapping) error {
+	args := m.Called(ctx, mapping)
+	return args.Error(0)
+}
+
+func (m *claimMappingRepoMock) Update(ctx context.Context, mapping domain.ClaimMapping) error {
+	args := m.Called(ctx, mapping)
+	return args.Error(0)
+}
+
+func (m *claimMappingRepoMock) Delete(ctx context.Context, appID, mappingID string) error {
+	args := m.Called(ctx, appID, mappingID)
+	return args.Error(0)
+}
+
+func (m *claimMappingRepoMock) Get(ctx context.Context, appID, mappingID string) (domain.ClaimMapping, error) {
+	args := m.Called(ctx, appID, mappingID)
+	return args.Get(0).(domain.ClaimMapping), args.Error(1)
+}
+
+func (m *claimMappingRepoMock) ListByAppID(ctx context.Context, appID string) ([]domain.ClaimMapping, error) {
+	args := m.Called(ctx, appID)
+	return args.Get(0).([]domain.ClaimMapping), args.Error(1)
+}
+
+func (m *claimMappingRepoMock) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.ClaimMapping], error) {
+	args := m.Called(ctx, appID, page)
+	return args.Get(0).(domain.Page[domain.ClaimMapping]), args.Error(1)
+}
+
+type apiKeyRepoMock struct{ mock.Mock }
+
+func (m *apiKeyRepoMock) Create(ctx context.Context, key domain.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *apiKeyRepoMock) Get(ctx context.Context, keyID string) (domain.APIKey, error) {
+	args := m.Called(ctx, keyID)
+	return args.Get(0).(domain.APIKey), args.Error(1)
+}
+
+func (m *apiKeyRepoMock) List(ctx context.Context, page domain.PageRequest) (domain.Page[domain.APIKey], error) {
+	args := m.Called(ctx, page)
+	return args.Get(0).(domain.Page[domain.APIKey]), args.Error(1)
+}
+
+func (m *apiKeyRepoMock) Revoke(ctx context.Context, keyID string, at time.Time) error {
+	args := m.Called(ctx, keyID, at)
+	return args.Error(0)
+}
+
 func TestApplicationService_Create(t *testing.T) {
 	repo := new(appRepoMock)
-	svc := NewApplicationService(repo)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
 
 	repo.On("Create", mock.Anything, mock.MatchedBy(func(app domain.Application) bool {
-		return app.ID == "app-1" && app.Name == "MyApp" && !app.CreatedAt.IsZero() && !app.UpdatedAt.IsZero()
+		return app.ID == "app-1" && app.Name == "MyApp" && app.IsStrict() && app.StrictMode != nil && !app.CreatedAt.IsZero() && !app.UpdatedAt.IsZero()
 	})).Return(nil)
 
 	err := svc.Create(context.Background(), domain.Application{ID: "app-1", Name: "MyApp"})
 	require.NoError(t, err)
+	assert.ErrorIs(t, svc.Create(context.Background(), domain.Application{ID: "app#1", Name: "MyApp"}), domain.ErrInvalidInput)
+	assert.ErrorIs(t, svc.Create(context.Background(), domain.Application{ID: "foo:rbac-admin", Name: "MyApp"}), domain.ErrInvalidInput)
 	repo.AssertExpectations(t)
 }
 
 func TestApplicationService_GetByID(t *testing.T) {
 	repo := new(appRepoMock)
-	svc := NewApplicationService(repo)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
 	expected := domain.Application{ID: "app-1", Name: "my app"}
 	repo.On("GetByID", mock.Anything, "app-1").Return(expected, nil)
 
@@ -95,7 +285,7 @@ func TestApplicationService_GetByID(t *testing.T) {
 
 func TestApplicationService_Update(t *testing.T) {
 	repo := new(appRepoMock)
-	svc := NewApplicationService(repo)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
 
 	repo.On("Update", mock.Anything, mock.MatchedBy(func(app domain.Application) bool {
 		return app.ID == "app-1" && app.Name == "my app"
@@ -108,44 +298,443 @@ func TestApplicationService_Update(t *testing.T) {
 
 func TestApplicationService_InvalidInput(t *testing.T) {
 	repo := new(appRepoMock)
-	svc := NewApplicationService(repo)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
 
 	err := svc.Create(context.Background(), domain.Application{ID: "", Name: ""})
 	assert.ErrorIs(t, err, domain.ErrInvalidInput)
 }
 
+func TestApplicationService_List(t *testing.T) {
+	repo := new(appRepoMock)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
+	expected := domain.Page[domain.Application]{Items: []domain.Application{{ID: "app-1", Name: "Billing"}}, NextToken: "tok"}
+	repo.On("List", mock.Anything, domain.ApplicationQuery{
+		NamePrefix: "Bil",
+		Descending: true,
+		Page:       domain.PageRequest{Limit: domain.DefaultPageLimit},
+	}).Return(expected, nil)
+
+	got, err := svc.List(context.Background(), domain.ApplicationQuery{NamePrefix: "Bil", Descending: true})
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestApplicationService_ListInvalidLimit(t *testing.T) {
+	repo := new(appRepoMock)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
+
+	_, err := svc.List(context.Background(), domain.ApplicationQuery{Page: domain.PageRequest{Limit: domain.MaxPageLimit + 1}})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+}
+
+func TestApplicationService_DeleteRefusesWithAssignments(t *testing.T) {
+	repo := new(appRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewApplicationService(repo, userRepo)
+	repo.On("GetByID", mock.Anything, "app-1").Return(domain.Application{ID: "app-1"}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "app-1", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{UserID: "u0", AppID: "app-1", Roles: []string{}}}, NextToken: "n1"}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "app-1", domain.PageRequest{Limit: domain.MaxPageLimit, NextToken: "n1"}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{UserID: "u1", AppID: "app-1", Groups: []string{"ops"}}}}, nil)
+
+	_, err := svc.Delete(context.Background(), "app-1", false, "")
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplicationService_DeleteForced(t *testing.T) {
+	repo := new(appRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewApplicationService(repo, userRepo)
+	expected := domain.ApplicationDeletion{AppID: "app-1", RolesRemoved: 2, AssignmentsRemoved: 3, Completed: true}
+	repo.On("GetByID", mock.Anything, "app-1").Return(domain.Application{ID: "app-1"}, nil)
+	repo.On("Delete", mock.Anything, "app-1", "tok").Return(expected, nil)
+
+	got, err := svc.Delete(context.Background(), "app-1", true, "tok")
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+	userRepo.AssertNotCalled(t, "ListByAppID", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestApplicationService_DeleteWithoutAssignments(t *testing.T) {
+	repo := new(appRepoMock)
+	userRepo := new(userRoleRepoMock)
+	svc := NewApplicationService(repo, userRepo)
+	repo.On("GetByID", mock.Anything, "app-1").Return(domain.Application{ID: "app-1"}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "app-1", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{UserID: "u1", AppID: "app-1", Roles: []string{}}}}, nil)
+	repo.On("Delete", mock.Anything, "app-1", "").Return(domain.ApplicationDeletion{AppID: "app-1", Completed: true}, nil)
+
+	got, err := svc.Delete(context.Background(), "app-1", false, "")
+	require.NoError(t, err)
+	assert.True(t, got.Completed)
+}
+
+func TestApplicationService_DeleteNotFound(t *testing.T) {
+	repo := new(appRepoMock)
+	svc := NewApplicationService(repo, new(userRoleRepoMock))
+	repo.On("GetByID", mock.Anything, "missing").Return(domain.Application{}, domain.ErrNotFound)
+
+	_, err := svc.Delete(context.Background(), "missing", true, "")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
 func TestRoleService_Create(t *testing.T) {
 	repo := new(roleRepoMock)
-	svc := NewRoleService(repo)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
 	repo.On("Create", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
 		return role.AppID == "a1" && role.ID == "r1" && role.Name == "admin"
 	})).Return(nil)
 
 	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "admin"})
 	require.NoError(t, err)
+	assert.ErrorIs(t, svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r#1", Name: "admin"}), domain.ErrInvalidInput)
+	repo.AssertExpectations(t)
+}
+
+func TestRoleService_CreateRejectsUnknownPermissions(t *testing.T) {
+	repo := new(roleRepoMock)
+	permRepo := new(permissionRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), permRepo, appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	appRepo.On("GetByID", mock.Anything, "a1").Return(domain.Application{ID: "a1"}, nil)
+	permRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{{AppID: "a1", ID: "read"}}, nil)
+
+	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "admin", Permissions: []string{"read", "wirte", "delete", "wirte"}})
+
+	require.ErrorIs(t, err, domain.ErrUnprocessable)
+	var unknown *domain.UnknownPermissionsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"wirte", "delete"}, unknown.Permissions)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRoleService_CreateStrictWildcards(t *testing.T) {
+	repo := new(roleRepoMock)
+	permRepo := new(permissionRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), permRepo, appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	appRepo.On("GetByID", mock.Anything, "a1").Return(domain.Application{ID: "a1"}, nil)
+	permRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{{AppID: "a1", ID: "documents:read"}}, nil)
+	repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+
+	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "reader", Permissions: []string{"documents:*", "*"}})
+	require.NoError(t, err)
+
+	err = svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r2", Name: "billing", Permissions: []string{"invoices:*"}})
+	var unknown *domain.UnknownPermissionsError
+	require.ErrorAs(t, err, &unknown)
+	assert.Equal(t, []string{"invoices:*"}, unknown.Permissions)
+	repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestRoleService_CreateRejectsInvalidPattern(t *testing.T) {
+	repo := new(roleRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "reader", Permissions: []string{"documents:re*d"}})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	appRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRoleService_CreateRejectsInvalidDenyPattern(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+
+	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "contractor", Deny: []string{"billing::export"}})
+
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRoleService_CreateRejectsInvalidCondition(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+
+	for _, conditions := range []map[string]string{
+		{"payments:approve": "amount < "},
+		{"payments:approve": "10000"},
+		{"payments:refund": "amount < 10000"},
+	} {
+		role := domain.Role{AppID: "a1", ID: "r1", Name: "approver", Permissions: []string{"payments:approve"}, Conditions: conditions}
+		err := svc.Create(context.Background(), role)
+		assert.ErrorIs(t, err, domain.ErrInvalidInput, conditions)
+	}
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRoleService_UpdateSkipsValidationWhenNotStrict(t *testing.T) {
+	repo := new(roleRepoMock)
+	permRepo := new(permissionRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), permRepo, appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	strict := false
+	appRepo.On("GetByID", mock.Anything, "a1").Return(domain.Application{ID: "a1", StrictMode: &strict}, nil)
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1", Name: "admin"}}, nil)
+	repo.On("Update", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	err := svc.Update(context.Background(), "a1", "r1", domain.RoleUpdate{Permissions: &[]string{"legacy"}})
+
+	require.NoError(t, err)
+	permRepo.AssertNotCalled(t, "ListByAppID", mock.Anything, mock.Anything)
 	repo.AssertExpectations(t)
 }
 
 func TestRoleService_UpdateAndList(t *testing.T) {
 	repo := new(roleRepoMock)
-	svc := NewRoleService(repo)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	stored := domain.Role{AppID: "a1", ID: "r1", Name: "admin", UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
 
 	repo.On("Update", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
-		return role.AppID == "a1" && role.ID == "r1"
-	})).Return(nil)
-	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1"}}, nil)
+		return role.AppID == "a1" && role.ID == "r1" && role.Name == "Admin"
+	}), []domain.Role{stored}).Return(nil)
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{stored}, nil)
 
-	err := svc.Update(context.Background(), domain.Role{AppID: "a1", ID: "r1", Name: "admin"})
+	name := "Admin"
+	err := svc.Update(context.Background(), "a1", "r1", domain.RoleUpdate{Name: &name})
 	require.NoError(t, err)
 
 	got, err := svc.ListByAppID(context.Background(), "a1")
 	require.NoError(t, err)
 	assert.Len(t, got, 1)
+	repo.AssertExpectations(t)
+}
+
+func TestRoleService_UpdateKeepsOmittedFields(t *testing.T) {
+	repo := new(roleRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	strict := false
+	appRepo.On("GetByID", mock.Anything, "a1").Return(domain.Application{ID: "a1", StrictMode: &strict}, nil)
+	roles := hierarchyRoles()
+	roles[2].Conditions = map[string]string{"doc:delete": `resource.owner == user.id`}
+	repo.On("ListByAppID", mock.Anything, "a1").Return(roles, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
+		return role.ID == "admin" && role.Name == "Admin" &&
+			slices.Equal(role.Parents, []string{"editor"}) &&
+			slices.Equal(role.Permissions, []string{"doc:delete", "doc:read"}) &&
+			role.Conditions["doc:delete"] != ""
+	}), []domain.Role{roles[2], roles[1], roles[0]}).Return(nil)
+
+	name := "Admin"
+	err := svc.Update(context.Background(), "a1", "admin", domain.RoleUpdate{Name: &name})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRoleService_UpdateClearsExplicitlyEmptiedFields(t *testing.T) {
+	repo := new(roleRepoMock)
+	appRepo := new(appRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), appRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	strict := false
+	appRepo.On("GetByID", mock.Anything, "a1").Return(domain.Application{ID: "a1", StrictMode: &strict}, nil)
+	roles := hierarchyRoles()
+	repo.On("ListByAppID", mock.Anything, "a1").Return(roles, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
+		return role.ID == "editor" && len(role.Parents) == 0
+	}), []domain.Role{roles[1]}).Return(nil)
+
+	err := svc.Update(context.Background(), "a1", "editor", domain.RoleUpdate{Parents: &[]string{}})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRoleService_UpdateNotFound(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.Update(context.Background(), "a1", "ghost", domain.RoleUpdate{})
+	require.ErrorIs(t, err, domain.ErrNotFound)
+
+	empty := ""
+	err = svc.Update(context.Background(), "a1", "viewer", domain.RoleUpdate{Name: &empty})
+	require.ErrorIs(t, err, domain.ErrInvalidInput)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoleService_ListPage(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	expected := domain.Page[domain.Role]{Items: []domain.Role{{AppID: "a1", ID: "r1"}}, NextToken: "tok"}
+	repo.On("ListPageByAppID", mock.Anything, "a1", domain.PageRequest{Limit: 5, NextToken: "prev"}).Return(expected, nil)
+
+	got, err := svc.ListPageByAppID(context.Background(), "a1", domain.PageRequest{Limit: 5, NextToken: "prev"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	_, err = svc.ListPageByAppID(context.Background(), "a1", domain.PageRequest{Limit: -1})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestRoleService_DeleteStripsAssignments(t *testing.T) {
+	repo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	groupRepo := new(groupRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewRoleService(repo, userRepo, new(permissionRepoMock), new(appRepoMock), groupRepo, mappingRepo)
+
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1"}, {AppID: "a1", ID: "r2"}}, nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.ClaimMapping{
+		{AppID: "a1", ID: "m1", RoleID: "r1"},
+		{AppID: "a1", ID: "m2", RoleID: "r2"},
+		{AppID: "a1", ID: "m3", RoleID: "r1"},
+	}, nil)
+	mappingRepo.On("Delete", mock.Anything, "a1", "m1").Return(nil)
+	mappingRepo.On("Delete", mock.Anything, "a1", "m3").Return(domain.ErrNotFound)
+	userRepo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{
+			{UserID: "u1", Roles: []string{"r1", "r2"}},
+			{UserID: "u2", Roles: []string{"r2"}},
+		}, NextToken: "next"}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.MaxPageLimit, NextToken: "next"}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{UserID: "u3", Roles: []string{"r1"}}}}, nil)
+	userRepo.On("RevokeRole", mock.Anything, "a1", "u1", "r1").Return(nil)
+	userRepo.On("RevokeRole", mock.Anything, "a1", "u3", "r1").Return(domain.ErrNotFound)
+	groupRepo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.Group]{Items: []domain.Group{
+			{AppID: "a1", ID: "g1", Roles: []string{"r1", "r2"}},
+			{AppID: "a1", ID: "g2", Roles: []string{"r2"}},
+		}}, nil)
+	groupRepo.On("RevokeRole", mock.Anything, "a1", "g1", "r1").Return(nil)
+	repo.On("Delete", mock.Anything, "a1", "r1").Return(nil)
+
+	got, err := svc.Delete(context.Background(), "a1", "r1", false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, got.AffectedUsers)
+	assert.Equal(t, []string{"u1", "u3"}, got.UserIDs)
+	assert.Equal(t, []string{"g1"}, got.Groups)
+	assert.Equal(t, []string{"m1", "m3"}, got.ClaimMappings)
+	repo.AssertExpectations(t)
+	userRepo.AssertExpectations(t)
+	groupRepo.AssertExpectations(t)
+	mappingRepo.AssertExpectations(t)
+}
+
+func TestRoleService_DeleteDryRun(t *testing.T) {
+	repo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	groupRepo := new(groupRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewRoleService(repo, userRepo, new(permissionRepoMock), new(appRepoMock), groupRepo, mappingRepo)
+
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1"}}, nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.ClaimMapping{{AppID: "a1", ID: "m1", RoleID: "r1"}}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "a1", mock.Anything).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{UserID: "u1", Roles: []string{"r1"}}}}, nil)
+	groupRepo.On("ListByAppID", mock.Anything, "a1", mock.Anything).
+		Return(domain.Page[domain.Group]{Items: []domain.Group{{AppID: "a1", ID: "g1", Roles: []string{"r1"}}}}, nil)
+
+	got, err := svc.Delete(context.Background(), "a1", "r1", true)
+	require.NoError(t, err)
+	assert.True(t, got.DryRun)
+	assert.Equal(t, []string{"u1"}, got.UserIDs)
+	assert.Equal(t, []string{"g1"}, got.Groups)
+	assert.Equal(t, []string{"m1"}, got.ClaimMappings)
+	mappingRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "RevokeRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	groupRepo.AssertNotCalled(t, "RevokeRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoleService_DeleteNotFound(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "other"}}, nil)
+
+	_, err := svc.Delete(context.Background(), "a1", "r1", false)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func hierarchyRoles() []domain.Role {
+	return []domain.Role{
+		{AppID: "a1", ID: "viewer", Permissions: []string{"doc:read"}, Deny: []string{"doc:purge"}},
+		{AppID: "a1", ID: "editor", Parents: []string{"viewer"}, Permissions: []string{"doc:write"}},
+		{AppID: "a1", ID: "admin", Parents: []string{"editor"}, Permissions: []string{"doc:delete", "doc:read"}},
+	}
+}
+
+func TestRoleService_GetResolvesInheritedPermissions(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	got, err := svc.Get(context.Background(), "a1", "admin")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"doc:delete", "doc:read"}, got.Permissions)
+	assert.Equal(t, []string{"doc:delete", "doc:read", "doc:write"}, got.EffectivePermissions)
+	assert.Equal(t, []string{"doc:purge"}, got.EffectiveDeny)
+
+	_, err = svc.Get(context.Background(), "a1", "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestRoleService_ListPageResolvesAgainstAllRoles(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListPageByAppID", mock.Anything, "a1", domain.PageRequest{Limit: 1}).
+		Return(domain.Page[domain.Role]{Items: hierarchyRoles()[1:2], NextToken: "tok"}, nil)
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	got, err := svc.ListPageByAppID(context.Background(), "a1", domain.PageRequest{Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, got.Items, 1)
+	assert.Equal(t, []string{"doc:write", "doc:read"}, got.Items[0].EffectivePermissions)
+}
+
+func TestRoleService_UpdateRejectsCycle(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.Update(context.Background(), "a1", "viewer", domain.RoleUpdate{Parents: &[]string{"admin"}})
+	require.ErrorIs(t, err, domain.ErrUnprocessable)
+	assert.Contains(t, err.Error(), "viewer -> admin -> editor -> viewer")
+
+	err = svc.Update(context.Background(), "a1", "viewer", domain.RoleUpdate{Parents: &[]string{"viewer"}})
+	assert.ErrorIs(t, err, domain.ErrUnprocessable)
+	repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestRoleService_CreateRejectsUnknownParent(t *testing.T) {
+	repo := new(roleRepoMock)
+	svc := NewRoleService(repo, new(userRoleRepoMock), new(permissionRepoMock), new(appRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	err := svc.Create(context.Background(), domain.Role{AppID: "a1", ID: "owner", Name: "Owner", Parents: []string{"admin", "ghost"}})
+	require.ErrorIs(t, err, domain.ErrUnprocessable)
+	assert.Contains(t, err.Error(), "ghost")
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestRoleService_DeleteStripsParentFromChildren(t *testing.T) {
+	repo := new(roleRepoMock)
+	userRepo := new(userRoleRepoMock)
+	groupRepo := new(groupRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewRoleService(repo, userRepo, new(permissionRepoMock), new(appRepoMock), groupRepo, mappingRepo)
+	repo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.ClaimMapping{}, nil)
+	userRepo.On("ListByAppID", mock.Anything, "a1", mock.Anything).Return(domain.Page[domain.UserAppRoles]{}, nil)
+	groupRepo.On("ListByAppID", mock.Anything, "a1", mock.Anything).Return(domain.Page[domain.Group]{}, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
+		return role.ID == "editor" && len(role.Parents) == 0 && slices.Equal(role.Permissions, []string{"doc:write"})
+	}), hierarchyRoles()[1:2]).Return(nil)
+	repo.On("Delete", mock.Anything, "a1", "viewer").Return(nil)
+
+	got, err := svc.Delete(context.Background(), "a1", "viewer", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor"}, got.ChildRoles)
+	repo.AssertExpectations(t)
 }
 
 func TestPermissionService_Create(t *testing.T) {
 	repo := new(permissionRepoMock)
-	svc := NewPermissionService(repo)
+	svc := NewPermissionService(repo, new(roleRepoMock))
 	repo.On("Create", mock.Anything, mock.MatchedBy(func(p domain.Permission) bool {
 		return p.AppID == "a1" && p.ID == "p1" && p.Name == "read"
 	})).Return(nil)
@@ -157,7 +746,7 @@ func TestPermissionService_Create(t *testing.T) {
 
 func TestPermissionService_List(t *testing.T) {
 	repo := new(permissionRepoMock)
-	svc := NewPermissionService(repo)
+	svc := NewPermissionService(repo, new(roleRepoMock))
 	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{{AppID: "a1", ID: "p1"}}, nil)
 
 	got, err := svc.ListByAppID(context.Background(), "a1")
@@ -165,15 +754,82 @@ func TestPermissionService_List(t *testing.T) {
 	assert.Len(t, got, 1)
 }
 
+func TestPermissionService_ListPage(t *testing.T) {
+	repo := new(permissionRepoMock)
+	svc := NewPermissionService(repo, new(roleRepoMock))
+	expected := domain.Page[domain.Permission]{Items: []domain.Permission{{AppID: "a1", ID: "p1"}}}
+	repo.On("ListPageByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.DefaultPageLimit, NextToken: "prev"}).Return(expected, nil)
+
+	got, err := svc.ListPageByAppID(context.Background(), "a1", domain.PageRequest{NextToken: "prev"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestPermissionService_Update(t *testing.T) {
+	repo := new(permissionRepoMock)
+	svc := NewPermissionService(repo, new(roleRepoMock))
+	repo.On("Update", mock.Anything, domain.Permission{AppID: "a1", ID: "p1", Name: "Read", Description: "fixed"}).Return(nil)
+
+	err := svc.Update(context.Background(), domain.Permission{AppID: "a1", ID: "p1", Name: "Read", Description: "fixed"})
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+
+	err = svc.Update(context.Background(), domain.Permission{AppID: "a1", ID: "p1"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestPermissionService_DeleteRefusedWhileReferenced(t *testing.T) {
+	repo := new(permissionRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewPermissionService(repo, roleRepo)
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{{AppID: "a1", ID: "p1"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1", Permissions: []string{"p1", "p2"}}}, nil)
+
+	_, err := svc.Delete(context.Background(), "a1", "p1", false)
+	assert.ErrorIs(t, err, domain.ErrConflict)
+	assert.Contains(t, err.Error(), "r1")
+	repo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPermissionService_DeleteCascade(t *testing.T) {
+	repo := new(permissionRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewPermissionService(repo, roleRepo)
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{{AppID: "a1", ID: "p1"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{AppID: "a1", ID: "r1", Name: "admin", Permissions: []string{"p1", "p2"}},
+		{AppID: "a1", ID: "r2", Name: "viewer", Permissions: []string{"p2"}},
+	}, nil)
+	roleRepo.On("Update", mock.Anything, mock.MatchedBy(func(role domain.Role) bool {
+		return role.ID == "r1" && slices.Equal(role.Permissions, []string{"p2"})
+	}), mock.Anything).Return(nil)
+	repo.On("Delete", mock.Anything, "a1", "p1").Return(nil)
+
+	got, err := svc.Delete(context.Background(), "a1", "p1", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r1"}, got.RolesUpdated)
+	roleRepo.AssertExpectations(t)
+	repo.AssertExpectations(t)
+}
+
+func TestPermissionService_DeleteNotFound(t *testing.T) {
+	repo := new(permissionRepoMock)
+	svc := NewPermissionService(repo, new(roleRepoMock))
+	repo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Permission{}, nil)
+
+	_, err := svc.Delete(context.Background(), "a1", "p1", true)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
 func TestUserService_AssignRole(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewUserService(userRepo, roleRepo)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{AppID: "a1", ID: "r1", Name: "admin"}}, nil)
-	userRepo.On("AssignRole", mock.Anything, "a1", "u1", "r1").Return(nil)
+	userRepo.On("AssignRole", mock.Anything, "a1", "u1", domain.RoleGrant{RoleID: "r1"}).Return(nil)
 
-	err := svc.AssignRole(context.Background(), "a1", "u1", "r1")
+	err := svc.AssignRole(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "r1"})
 	require.NoError(t, err)
 	userRepo.AssertExpectations(t)
 }
@@ -181,7 +837,7 @@ func TestUserService_AssignRole(t *testing.T) {
 func TestUserService_GetUserAppRoles(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewUserService(userRepo, roleRepo)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"r1"}}, nil)
 	out, err := svc.GetUserAppRoles(context.Background(), "a1", "u1")
@@ -192,17 +848,217 @@ func TestUserService_GetUserAppRoles(t *testing.T) {
 func TestUserService_AssignRoleNotFound(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewUserService(userRepo, roleRepo)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{ID: "other"}}, nil)
 
-	err := svc.AssignRole(context.Background(), "a1", "u1", "r1")
+	err := svc.AssignRole(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "r1"})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserService_RevokeRole(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("RevokeRole", mock.Anything, "a1", "u1", "r1").Return(nil)
+
+	err := svc.RevokeRole(context.Background(), "a1", "u1", "r1")
+	require.NoError(t, err)
+	userRepo.AssertExpectations(t)
+}
+
+func TestUserService_RevokeRoleWithoutAssignment(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("RevokeRole", mock.Anything, "a1", "u1", "r1").Return(domain.ErrNotFound)
+
+	err := svc.RevokeRole(context.Background(), "a1", "u1", "r1")
 	assert.ErrorIs(t, err, domain.ErrNotFound)
 }
 
+func TestUserService_RevokeAllRoles(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("RevokeAllRoles", mock.Anything, "a1", "u1").Return(nil)
+
+	err := svc.RevokeAllRoles(context.Background(), "a1", "u1")
+	require.NoError(t, err)
+	userRepo.AssertExpectations(t)
+
+	err = svc.RevokeAllRoles(context.Background(), "a1", "")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestUserService_ListAppUsers(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewUserService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	expected := domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{AppID: "a1", UserID: "u1", Roles: []string{"r1"}}}, NextToken: "tok"}
+	userRepo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: 10, NextToken: "prev"}).Return(expected, nil)
+
+	got, err := svc.ListAppUsers(context.Background(), "a1", domain.PageRequest{Limit: 10, NextToken: "prev"})
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+}
+
+func TestUserService_ListRoleMembers(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewUserService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	expected := domain.Page[domain.RoleMember]{Items: []domain.RoleMember{{AppID: "a1", RoleID: "admin", UserID: "u1"}}}
+	userRepo.On("ListByRole", mock.Anything, "a1", "admin", domain.PageRequest{Limit: domain.DefaultPageLimit}).Return(expected, nil)
+
+	got, err := svc.ListRoleMembers(context.Background(), "a1", "admin", domain.PageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, expected, got)
+
+	_, err = svc.ListRoleMembers(context.Background(), "a1", "", domain.PageRequest{})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestUserService_ListRoleMembersHidesExpired(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewUserService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	userRepo.On("ListByRole", mock.Anything, "a1", "oncall", mock.Anything).Return(domain.Page[domain.RoleMember]{Items: []domain.RoleMember{
+		{AppID: "a1", RoleID: "oncall", UserID: "u1", ExpiresAt: &past},
+		{AppID: "a1", RoleID: "oncall", UserID: "u2", ExpiresAt: &future},
+		{AppID: "a1", RoleID: "oncall", UserID: "u3"},
+	}}, nil)
+
+	got, err := svc.ListRoleMembers(context.Background(), "a1", "oncall", domain.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, got.Items, 2)
+	assert.Equal(t, "u2", got.Items[0].UserID)
+	assert.Equal(t, "u3", got.Items[1].UserID)
+}
+
+func TestUserService_AssignRoleValidatesWindow(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	past := time.Now().Add(-time.Hour)
+	start := time.Now().Add(2 * time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	err := svc.AssignRole(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "r1", ExpiresAt: &past})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	err = svc.AssignRole(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "r1", StartsAt: &start, ExpiresAt: &end})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_AssignRoleValidatesScope(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	for _, scope := range []string{"org//acme", "org/acme/", "org/ac me"} {
+		err := svc.AssignRole(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "r1", Scope: scope})
+		assert.ErrorIs(t, err, domain.ErrInvalidInput, scope)
+	}
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_AssignRoleRejectsKeySeparator(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewUserService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+
+	assert.ErrorIs(t, svc.AssignRole(context.Background(), "a1", "b#c", domain.RoleGrant{RoleID: "a"}), domain.ErrInvalidInput)
+	assert.ErrorIs(t, svc.AssignRole(context.Background(), "a1", "c", domain.RoleGrant{RoleID: "a#b"}), domain.ErrInvalidInput)
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_RevokeGrant(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	grant := domain.RoleGrant{RoleID: "editor", Scope: "org/acme"}
+
+	userRepo.On("RevokeGrant", mock.Anything, "a1", "u1", grant).Return(nil)
+
+	require.NoError(t, svc.RevokeGrant(context.Background(), "a1", "u1", grant))
+	assert.ErrorIs(t, svc.RevokeGrant(context.Background(), "a1", "u1", domain.RoleGrant{RoleID: "editor"}), domain.ErrInvalidInput)
+	userRepo.AssertNumberOfCalls(t, "RevokeGrant", 1)
+}
+
+func TestUserService_PruneExpired(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewUserService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	past := time.Now().Add(-time.Hour)
+	userRepo.On("ListByAppID", mock.Anything, "a1", domain.PageRequest{Limit: domain.MaxPageLimit}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{
+			{UserID: "u1", Roles: []string{"viewer", "oncall"}, Grants: []domain.RoleGrant{{RoleID: "viewer"}, {RoleID: "oncall", ExpiresAt: &past}}},
+			{UserID: "u2", Roles: []string{"viewer"}, Grants: []domain.RoleGrant{{RoleID: "viewer"}}},
+			{UserID: "u3", Roles: []string{"oncall"}, Grants: []domain.RoleGrant{{RoleID: "oncall", ExpiresAt: &past}}},
+		}}, nil)
+	userRepo.On("PruneExpired", mock.Anything, "a1", "u1").Return(nil)
+	// u3's assignment was removed after the listing.
+	userRepo.On("PruneExpired", mock.Anything, "a1", "u3").Return(domain.ErrNotFound)
+
+	pruned, err := svc.PruneExpired(context.Background(), "a1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	userRepo.AssertExpectations(t)
+}
+
+func TestUserService_ListUserApplications(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("ListByUser", mock.Anything, "u1", domain.PageRequest{Limit: domain.DefaultPageLimit}).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{{AppID: "a1", UserID: "u1", Roles: []string{"r1"}}}, NextToken: "tok"}, nil)
+
+	got, err := svc.ListUserApplications(context.Background(), "u1", false, domain.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, got.Items, 1)
+	assert.Equal(t, "a1", got.Items[0].AppID)
+	assert.Nil(t, got.Items[0].Permissions)
+	assert.Equal(t, "tok", got.NextToken)
+	roleRepo.AssertNotCalled(t, "ListByAppID", mock.Anything, mock.Anything)
+}
+
+func TestUserService_ListUserApplicationsWithPermissions(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewUserService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	past := time.Now().Add(-time.Hour)
+	userRepo.On("ListByUser", mock.Anything, "u1", mock.Anything).
+		Return(domain.Page[domain.UserAppRoles]{Items: []domain.UserAppRoles{
+			{AppID: "a1", UserID: "u1", Roles: []string{"r1", "r2", "gone", "old"}, Grants: []domain.RoleGrant{{RoleID: "r1"}, {RoleID: "r2"}, {RoleID: "gone"}, {RoleID: "old", ExpiresAt: &past}}},
+			{AppID: "a2", UserID: "u1", Roles: []string{"r1"}, Grants: []domain.RoleGrant{{RoleID: "r1", ExpiresAt: &past}}},
+			{AppID: "a3", UserID: "u1", Roles: []string{"viewer", "editor"}, Grants: []domain.RoleGrant{
+				{RoleID: "viewer", Source: domain.ClaimMappingSource("readers")},
+				{RoleID: "editor", Source: domain.ClaimMappingSource("writers")},
+			}},
+		}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a3").Return(hierarchyRoles(), nil)
+	mappingRepo.On("ListByAppID", mock.Anything, "a3").Return([]domain.ClaimMapping{
+		{AppID: "a3", ID: "readers", Claim: "cognito:groups", Value: "readers", RoleID: "viewer", Sync: true},
+	}, nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "r1", Permissions: []string{"doc:read", "doc:write"}},
+		{ID: "r2", Permissions: []string{"doc:read", "doc:share"}},
+		{ID: "old", Permissions: []string{"doc:purge"}},
+	}, nil)
+
+	got, err := svc.ListUserApplications(context.Background(), "u1", true, domain.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, got.Items, 2)
+	assert.Equal(t, []string{"r1", "r2", "gone"}, got.Items[0].Roles)
+	assert.Equal(t, []string{"doc:read", "doc:write", "doc:share"}, got.Items[0].Permissions)
+	assert.Equal(t, "a3", got.Items[1].AppID)
+	assert.Equal(t, []string{"doc:read"}, got.Items[1].Permissions)
+	userRepo.AssertNotCalled(t, "GetByUserAndApp", mock.Anything, mock.Anything, mock.Anything)
+	mappingRepo.AssertExpectations(t)
+}
+
 func TestAuthorizationService_Allowed(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewAuthorizationService(userRepo, roleRepo)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"admin"}}, nil)
 	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{ID: "admin", Permissions: []string{"perm:write"}}}, nil)
@@ -215,7 +1071,7 @@ func TestAuthorizationService_Allowed(t *testing.T) {
 func TestAuthorizationService_InvalidInput(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewAuthorizationService(userRepo, roleRepo)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	allowed, err := svc.IsAllowed(context.Background(), "", "u1", "perm:read")
 	assert.False(t, allowed)
@@ -225,7 +1081,7 @@ func TestAuthorizationService_InvalidInput(t *testing.T) {
 func TestAuthorizationService_Denied(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewAuthorizationService(userRepo, roleRepo)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"viewer"}}, nil)
 	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{ID: "viewer", Permissions: []string{"perm:read"}}}, nil)
@@ -235,10 +1091,374 @@ func TestAuthorizationService_Denied(t *testing.T) {
 	assert.False(t, allowed)
 }
 
+func TestAuthorizationService_AllowedByInheritedPermission(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"admin"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return(hierarchyRoles(), nil)
+
+	allowed, err := svc.IsAllowed(context.Background(), "a1", "u1", "doc:write")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestAuthorizationService_WildcardGrants(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"editor"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{ID: "editor", Permissions: []string{"documents:*"}}}, nil)
+
+	allowed, err := svc.IsAllowed(context.Background(), "a1", "u1", "documents:read:own")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = svc.IsAllowed(context.Background(), "a1", "u1", "invoices:read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	_, err = svc.IsAllowed(context.Background(), "a1", "u1", "documents:*")
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestAuthorizationService_DenyOverridesGrant(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"finance", "contractor"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "finance", Permissions: []string{"billing:*"}},
+		{ID: "restricted", Deny: []string{"billing:export"}},
+		{ID: "contractor", Parents: []string{"restricted"}},
+	}, nil)
+
+	decision, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "billing:export"})
+	require.NoError(t, err)
+	assert.False(t, decision.Allowed)
+	require.NotNil(t, decision.DeniedBy)
+	assert.Equal(t, domain.DenyRule{RoleID: "restricted", Pattern: "billing:export"}, *decision.DeniedBy)
+	assert.ErrorIs(t, decision.DeniedBy.Err(), domain.ErrPermissionDeny)
+	assert.NotEmpty(t, decision.Reason)
+
+	decision, err = svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "billing:read"})
+	require.NoError(t, err)
+	assert.True(t, decision.Allowed)
+	assert.Nil(t, decision.DeniedBy)
+}
+
+func TestAuthorizationService_IgnoresGrantsOutsideWindow(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		AppID:  "a1",
+		UserID: "u1",
+		Roles:  []string{"expired", "pending", "oncall"},
+		Grants: []domain.RoleGrant{
+			{RoleID: "expired", ExpiresAt: &past},
+			{RoleID: "pending", StartsAt: &future},
+			{RoleID: "oncall", StartsAt: &past, ExpiresAt: &future},
+		},
+	}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "expired", Permissions: []string{"doc:delete"}},
+		{ID: "pending", Permissions: []string{"doc:write"}},
+		{ID: "oncall", Permissions: []string{"doc:read"}},
+	}, nil)
+
+	for permission, want := range map[string]bool{"doc:delete": false, "doc:write": false, "doc:read": true} {
+		allowed, err := svc.IsAllowed(context.Background(), "a1", "u1", permission)
+		require.NoError(t, err)
+		assert.Equal(t, want, allowed, permission)
+	}
+}
+
+func TestAuthorizationService_ScopedGrants(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		AppID:  "a1",
+		UserID: "u1",
+		Roles:  []string{"viewer", "editor"},
+		Grants: []domain.RoleGrant{
+			{RoleID: "viewer"},
+			{RoleID: "editor", Scope: "org/acme"},
+		},
+	}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "viewer", Permissions: []string{"doc:read"}},
+		{ID: "editor", Permissions: []string{"doc:write"}},
+	}, nil)
+
+	cases := []struct {
+		permission string
+		resource   string
+		want       bool
+	}{
+		{"doc:write", "org/acme", true},
+		{"doc:write", "org/acme/project/42/doc/7", true},
+		{"doc:write", "org/acme-labs", false},
+		{"doc:write", "org", false},
+		{"doc:write", "", false},
+		{"doc:read", "org/acme-labs", true},
+		{"doc:read", "", true},
+	}
+	for _, tc := range cases {
+		decision, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: tc.permission, Resource: tc.resource})
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, decision.Allowed, "%s on %q", tc.permission, tc.resource)
+	}
+
+	_, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:read", Resource: "org//acme"})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+}
+
+func TestAuthorizationService_ConditionalGrants(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"approver"}}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "clerk", Permissions: []string{"payments:read"}},
+		{
+			ID:          "approver",
+			Parents:     []string{"clerk"},
+			Permissions: []string{"payments:approve"},
+			Conditions:  map[string]string{"payments:approve": "amount < 10000 && hour >= 9 && hour < 17"},
+		},
+	}, nil)
+
+	cases := []struct {
+		permission string
+		context    map[string]any
+		want       bool
+	}{
+		{"payments:approve", map[string]any{"amount": 2500.0, "hour": 10.0}, true},
+		{"payments:approve", map[string]any{"amount": 25000.0, "hour": 10.0}, false},
+		{"payments:approve", map[string]any{"amount": 2500.0, "hour": 20.0}, false},
+		{"payments:approve", map[string]any{"amount": 2500.0}, false},
+		{"payments:approve", nil, false},
+		{"payments:read", nil, true},
+	}
+	for _, tc := range cases {
+		decision, err := svc.Decide(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: tc.permission, Context: tc.context})
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, decision.Allowed, "%s with %v", tc.permission, tc.context)
+	}
+}
+
+func TestAuthorizationService_DecideBatchReadsOnce(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{AppID: "a1", UserID: "u1", Roles: []string{"viewer"}}, nil).Once()
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u2").Return(domain.UserAppRoles{}, domain.ErrNotFound).Once()
+	userRepo.On("GetByUserAndApp", mock.Anything, "a2", "u1").Return(domain.UserAppRoles{AppID: "a2", UserID: "u1", Roles: []string{"admin"}}, nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{{ID: "viewer", Permissions: []string{"doc:read"}}}, nil).Once()
+	roleRepo.On("ListByAppID", mock.Anything, "a2").Return([]domain.Role{{ID: "admin", Permissions: []string{"*"}}}, nil).Once()
+
+	decisions, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:read"},
+		{AppID: "a1", UserID: "u1", Permission: "doc:write"},
+		{AppID: "a1", UserID: "u2", Permission: "doc:read"},
+		{AppID: "a2", UserID: "u1", Permission: "doc:delete"},
+		{AppID: "a1", UserID: "u2", Permission: "doc:write"},
+		{AppID: "a1", UserID: "u1", Permission: "doc:read"},
+	})
+
+	require.NoError(t, err)
+	allowed := make([]bool, len(decisions))
+	for i, decision := range decisions {
+		allowed[i] = decision.Allowed
+	}
+	assert.Equal(t, []bool{true, false, false, true, false, true}, allowed)
+	userRepo.AssertExpectations(t)
+	roleRepo.AssertExpectations(t)
+}
+
+func TestAuthorizationService_DecideBatchValidatesFirst(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	_, err := svc.DecideBatch(context.Background(), []domain.AccessRequest{
+		{AppID: "a1", UserID: "u1", Permission: "doc:read"},
+		{AppID: "a1", UserID: "u1", Permission: "doc:*"},
+	})
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = svc.DecideBatch(context.Background(), nil)
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+
+	_, err = svc.DecideBatch(context.Background(), make([]domain.AccessRequest, domain.MaxAuthorizeBatch+1))
+	assert.ErrorIs(t, err, domain.ErrInvalidInput)
+	userRepo.AssertNotCalled(t, "GetByUserAndApp", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthorizationService_ExplainReasons(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	roles := []domain.Role{
+		{ID: "viewer", Permissions: []string{"doc:read"}},
+		{ID: "editor", Parents: []string{"viewer", "gone"}, Permissions: []string{"doc:*"}, Deny: []string{"doc:purge"}},
+		{ID: "approver", Permissions: []string{"pay:approve"}, Conditions: map[string]string{"pay:approve": "amount < 100"}},
+	}
+	cases := []struct {
+		name       string
+		userRoles  domain.UserAppRoles
+		userErr    error
+		permission string
+		want       string
+	}{
+		{"no assignment", domain.UserAppRoles{}, domain.ErrNotFound, "doc:read", domain.ReasonNoAssignment},
+		{"empty roles", domain.UserAppRoles{Roles: []string{}}, nil, "doc:read", domain.ReasonEmptyRoles},
+		{"expired", domain.UserAppRoles{Roles: []string{"viewer"}, Grants: []domain.RoleGrant{{RoleID: "viewer", ExpiresAt: &past}}}, nil, "doc:read", domain.ReasonNoActiveGrants},
+		{"dangling", domain.UserAppRoles{Roles: []string{"deleted"}}, nil, "doc:read", domain.ReasonDanglingRoles},
+		{"denied", domain.UserAppRoles{Roles: []string{"editor"}}, nil, "doc:purge", domain.ReasonDeniedByRule},
+		{"condition", domain.UserAppRoles{Roles: []string{"approver"}}, nil, "pay:approve", domain.ReasonConditionNotMet},
+		{"not granted", domain.UserAppRoles{Roles: []string{"viewer"}}, nil, "doc:write", domain.ReasonNotGranted},
+		{"granted", domain.UserAppRoles{Roles: []string{"editor"}}, nil, "doc:write", domain.ReasonGranted},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			userRepo := new(userRoleRepoMock)
+			roleRepo := new(roleRepoMock)
+			svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+			userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(tc.userRoles, tc.userErr)
+			roleRepo.On("ListByAppID", mock.Anything, "a1").Return(roles, nil)
+
+			explanation, err := svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: tc.permission})
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, explanation.Reason)
+			assert.Equal(t, tc.want == domain.ReasonGranted, explanation.Allowed)
+		})
+	}
+}
+
+func TestAuthorizationService_ExplainDetails(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+	past := time.Now().Add(-time.Hour)
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		Roles:  []string{"editor", "oncall", "deleted"},
+		Grants: []domain.RoleGrant{{RoleID: "editor"}, {RoleID: "oncall", ExpiresAt: &past}, {RoleID: "deleted"}},
+	}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "viewer", Permissions: []string{"doc:read"}},
+		{ID: "editor", Parents: []string{"viewer", "gone"}, Permissions: []string{"doc:write"}},
+		{ID: "oncall", Permissions: []string{"doc:*"}},
+	}, nil)
+
+	explanation, err := svc.Explain(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Permission: "doc:read"})
+
+	require.NoError(t, err)
+	assert.True(t, explanation.Allowed)
+	assert.Equal(t, []string{"editor", "oncall", "deleted"}, explanation.AssignedRoles)
+	assert.Equal(t, []string{"editor", "deleted"}, explanation.ActiveRoles)
+	assert.Equal(t, []string{"editor", "viewer"}, explanation.EvaluatedRoles)
+	assert.Equal(t, []string{"deleted", "gone"}, explanation.MissingRoles)
+	require.Len(t, explanation.InactiveGrants, 1)
+	assert.Equal(t, "oncall", explanation.InactiveGrants[0].RoleID)
+	assert.Equal(t, &domain.PermissionGrant{RoleID: "viewer", Pattern: "doc:read"}, explanation.GrantedBy)
+}
+
+func TestAuthorizationService_EffectivePermissions(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
+
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{
+		Roles:  []string{"editor", "approver", "auditor"},
+		Grants: []domain.RoleGrant{{RoleID: "editor"}, {RoleID: "approver"}, {RoleID: "auditor", Scope: "org/acme"}},
+	}, nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "viewer", Permissions: []string{"doc:read"}},
+		{ID: "editor", Parents: []string{"viewer"}, Permissions: []string{"doc:read", "doc:write"}, Deny: []string{"doc:purge"}},
+		{ID: "approver", Permissions: []string{"pay:approve"}, Conditions: map[string]string{"pay:approve": "amount < 100"}},
+		{ID: "auditor", Permissions: []string{"audit:read"}},
+	}, nil)
+
+	got, err := svc.EffectivePermissions(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor", "viewer", "approver"}, got.Roles)
+	assert.Equal(t, []string{"doc:read", "doc:write", "pay:approve"}, got.Permissions)
+	assert.Equal(t, []string{"pay:approve"}, got.Conditional)
+	assert.Equal(t, []string{"doc:purge"}, got.Deny)
+	assert.Nil(t, got.Grants)
+
+	got, err = svc.EffectivePermissions(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1", Resource: "org/acme/reports"}, true)
+	require.NoError(t, err)
+	assert.Contains(t, got.Permissions, "audit:read")
+	assert.Equal(t, []domain.PermissionGrant{
+		{RoleID: "editor", Pattern: "doc:read"},
+		{RoleID: "editor", Pattern: "doc:write"},
+		{RoleID: "viewer", Pattern: "doc:read"},
+		{RoleID: "approver", Pattern: "pay:approve", Condition: "amount < 100"},
+		{RoleID: "auditor", Pattern: "audit:read"},
+	}, got.Grants)
+	assert.Equal(t, []domain.DenyRule{{RoleID: "editor", Pattern: "doc:purge"}}, got.DenyRules)
+
+	for _, permission := range got.Permissions {
+		allowed, err := svc.IsAllowed(context.Background(), "a1", "u1", permission)
+		require.NoError(t, err)
+		assert.Equal(t, permission == "doc:read" || permission == "doc:write", allowed, permission)
+	}
+}
+
+func TestAuthorizationService_EffectivePermissionsMatchDecisions(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	roleRepo := new(roleRepoMock)
+	mappingRepo := new(claimMappingRepoMock)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), mappingRepo)
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound)
+	mappingRepo.On("ListByAppID", mock.Anything, "a1").Return(writersMapping(false), nil)
+	roleRepo.On("ListByAppID", mock.Anything, "a1").Return([]domain.Role{
+		{ID: "editor", Permissions: []string{"doc:read", "doc:purge", "doc:*"}, Deny: []string{"doc:purge"}},
+	}, nil)
+	req := domain.AccessRequest{AppID: "a1", UserID: "u1", Claims: writerClaims}
+
+	got, err := svc.EffectivePermissions(context.Background(), req, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor"}, got.Roles)
+	assert.Equal(t, []string{"doc:read", "doc:*"}, got.Permissions)
+	assert.Equal(t, []string{"doc:purge"}, got.Deny)
+	for _, permission := range []string{"doc:read", "doc:purge"} {
+		req.Permission = permission
+		decision, err := svc.Decide(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, slices.Contains(got.Permissions, permission), decision.Allowed, permission)
+	}
+}
+
+func TestAuthorizationService_EffectivePermissionsWithoutAssignment(t *testing.T) {
+	userRepo := new(userRoleRepoMock)
+	svc := NewAuthorizationService(userRepo, new(roleRepoMock), new(groupRepoMock), new(claimMappingRepoMock))
+	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound)
+
+	got, err := svc.EffectivePermissions(context.Background(), domain.AccessRequest{AppID: "a1", UserID: "u1"}, false)
+
+	require.NoError(t, err)
+	assert.Empty(t, got.Permissions)
+	assert.NotNil(t, got.Permissions)
+}
+
 func TestAuthorizationService_UserWithoutRole(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewAuthorizationService(userRepo, roleRepo)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, domain.ErrNotFound)
 
@@ -250,7 +1470,7 @@ func TestAuthorizationService_UserWithoutRole(t *testing.T) {
 func TestAuthorizationService_PropagatesErrors(t *testing.T) {
 	userRepo := new(userRoleRepoMock)
 	roleRepo := new(roleRepoMock)
-	svc := NewAuthorizationService(userRepo, roleRepo)
+	svc := NewAuthorizationService(userRepo, roleRepo, new(groupRepoMock), new(claimMappingRepoMock))
 
 	expectedErr := errors.New("db down")
 	userRepo.On("GetByUserAndApp", mock.Anything, "a1", "u1").Return(domain.UserAppRoles{}, expectedErr)
@@ -0,0 +1,52 @@
+package domain
+
+// AdminAppID is the reserved application whose roles govern the management
+// API.
+const AdminAppID = "rbac-admin"
+
+const SuperAdminRole = "super-admin"
+
+const (
+	PermAppsWrite    = "apps:write"
+	PermAPIKeysWrite = "apikeys:write"
+	PermUsersRead    = "users:read"
+)
+
+// The per-application permissions are not defined, so the admin application
+// is not strict.
+var AdminPermissions = []string{PermAppsWrite, PermAPIKeysWrite, PermUsersRead}
+
+func AppsReadPermission(appID string) string { return "apps:read:" + appID }
+
+func RolesWritePermission(appID string) string { return "roles:write:" + appID }
+
+func RolesReadPermission(appID string) string { return "roles:read:" + appID }
+
+func AssignmentsWritePermission(appID string) string { return "assignments:write:" + appID }
+
+func AssignmentsReadPermission(appID string) string { return "assignments:read:" + appID }
+
+func GroupsWritePermission(appID string) string { return "groups:write:" + appID }
+
+func GroupsReadPermission(appID string) string { return "groups:read:" + appID }
+
+func AuthorizeOthersPermission(appID string) string { return "authorize:others:" + appID }
+
+var appPermissions = []func(appID string) string{
+	AppsReadPermission,
+	RolesWritePermission, RolesReadPermission,
+	AssignmentsWritePermission, AssignmentsReadPermission,
+	GroupsWritePermission, GroupsReadPermission,
+	AuthorizeOthersPermission,
+}
+
+// IsAdminAppPermission reports whether permission is one of the
+// per-application permissions for AdminAppID itself.
+func IsAdminAppPermission(permission string) bool {
+	for _, appPermission := range appPermissions {
+		if permission == appPermission(AdminAppID) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAdminAppPermission(t *testing.T) {
+	assert.True(t, IsAdminAppPermission(RolesWritePermission(AdminAppID)))
+	assert.True(t, IsAdminAppPermission(AppsReadPermission(AdminAppID)))
+	assert.False(t, IsAdminAppPermission(RolesWritePermission("foo:"+AdminAppID)))
+	assert.False(t, IsAdminAppPermission(RolesWritePermission("docs")))
+	assert.False(t, IsAdminAppPermission(PermAppsWrite))
+}
@@ -1,9 +1,25 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"strings"
+)
 
 var (
 	ErrNotFound       = errors.New("not found")
 	ErrInvalidInput   = errors.New("invalid input")
 	ErrPermissionDeny = errors.New("permission denied")
+	ErrConflict       = errors.New("conflict")
+	ErrUnprocessable  = errors.New("unprocessable")
+	ErrUnauthorized   = errors.New("unauthorized")
 )
+
+type UnknownPermissionsError struct {
+	Permissions []string
+}
+
+func (e *UnknownPermissionsError) Error() string {
+	return "unknown permissions: " + strings.Join(e.Permissions, ", ")
+}
+
+func (e *UnknownPermissionsError) Unwrap() error { return ErrUnprocessable }
@@ -0,0 +1,28 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeySeparator joins IDs in storage keys, so IDs may not contain it.
+const KeySeparator = "#"
+
+func ValidateID(id string) error {
+	if strings.Contains(id, KeySeparator) {
+		return fmt.Errorf("%w: id %q must not contain %q", ErrInvalidInput, id, KeySeparator)
+	}
+	return nil
+}
+
+// App IDs also end management permissions, so they must be a single concrete
+// permission segment.
+func ValidateAppID(id string) error {
+	if err := ValidateID(id); err != nil {
+		return err
+	}
+	if strings.Contains(id, PermissionSeparator) || ValidatePermission(id) != nil {
+		return fmt.Errorf("%w: application id %q must be a single permission segment", ErrInvalidInput, id)
+	}
+	return nil
+}
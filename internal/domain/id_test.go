@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateID(t *testing.T) {
+	assert.NoError(t, ValidateID("editor"))
+	assert.NoError(t, ValidateID("user@example.com"))
+	assert.ErrorIs(t, ValidateID("a#b"), ErrInvalidInput)
+}
+
+func TestValidateAppID(t *testing.T) {
+	assert.NoError(t, ValidateAppID("billing-eu"))
+	for _, id := range []string{"a#b", "foo:rbac-admin", "*", "a*", "two words"} {
+		assert.ErrorIs(t, ValidateAppID(id), ErrInvalidInput, id)
+	}
+}
@@ -1,24 +1,214 @@
 package domain
 
-import "time"
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
 
 type Application struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// StrictMode nil means strict.
+	StrictMode *bool     `json:"strict_mode,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func (a Application) IsStrict() bool {
+	return a.StrictMode == nil || *a.StrictMode
 }
 
 type Role struct {
+	AppID                string            `json:"app_id"`
+	ID                   string            `json:"id"`
+	Name                 string            `json:"name"`
+	Parents              []string          `json:"parents,omitempty"`
+	Permissions          []string          `json:"permissions"`
+	Deny                 []string          `json:"deny,omitempty"`
+	Conditions           map[string]string `json:"conditions,omitempty"`
+	EffectivePermissions []string          `json:"effective_permissions"`
+	EffectiveDeny        []string          `json:"effective_deny,omitempty"`
+	CreatedAt            time.Time         `json:"created_at"`
+	UpdatedAt            time.Time         `json:"updated_at"`
+}
+
+// RoleUpdate leaves nil fields unchanged.
+type RoleUpdate struct {
+	Name        *string            `json:"name"`
+	Parents     *[]string          `json:"parents"`
+	Permissions *[]string          `json:"permissions"`
+	Deny        *[]string          `json:"deny"`
+	Conditions  *map[string]string `json:"conditions"`
+}
+
+func (u RoleUpdate) Apply(role Role) Role {
+	if u.Name != nil {
+		role.Name = *u.Name
+	}
+	if u.Parents != nil {
+		role.Parents = *u.Parents
+	}
+	if u.Permissions != nil {
+		role.Permissions = *u.Permissions
+	}
+	if u.Deny != nil {
+		role.Deny = *u.Deny
+	}
+	if u.Conditions != nil {
+		role.Conditions = *u.Conditions
+	}
+	return role
+}
+
+type DenyRule struct {
+	RoleID  string `json:"role_id"`
+	Pattern string `json:"pattern"`
+}
+
+func (r DenyRule) Err() error {
+	return fmt.Errorf("%w: role %s denies %s", ErrPermissionDeny, r.RoleID, r.Pattern)
+}
+
+// AccessRequest.Claims is only set when UserID is the authenticated caller.
+type AccessRequest struct {
+	AppID      string         `json:"app_id"`
+	UserID     string         `json:"user_id"`
+	Permission string         `json:"permission"`
+	Resource   string         `json:"resource,omitempty"`
+	Context    map[string]any `json:"context,omitempty"`
+	Claims     map[string]any `json:"-"`
+}
+
+type Decision struct {
+	Allowed  bool      `json:"allowed"`
+	DeniedBy *DenyRule `json:"denied_by,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+const (
+	ReasonGranted         = "granted"
+	ReasonNoAssignment    = "no_assignment"
+	ReasonEmptyRoles      = "empty_roles"
+	ReasonNoActiveGrants  = "no_active_grants"
+	ReasonDanglingRoles   = "dangling_roles"
+	ReasonDeniedByRule    = "denied_by_rule"
+	ReasonConditionNotMet = "condition_not_met"
+	ReasonNotGranted      = "not_granted"
+)
+
+type PermissionGrant struct {
+	RoleID    string `json:"role_id"`
+	Pattern   string `json:"pattern"`
+	Condition string `json:"condition,omitempty"`
+}
+
+type Explanation struct {
+	Allowed         bool              `json:"allowed"`
+	Reason          string            `json:"reason"`
+	AssignedRoles   []string          `json:"assigned_roles"`
+	Groups          []string          `json:"groups,omitempty"`
+	MappedRoles     []string          `json:"mapped_roles,omitempty"`
+	ActiveRoles     []string          `json:"active_roles"`
+	InactiveGrants  []RoleGrant       `json:"inactive_grants,omitempty"`
+	EvaluatedRoles  []string          `json:"evaluated_roles"`
+	MissingRoles    []string          `json:"missing_roles,omitempty"`
+	GrantedBy       *PermissionGrant  `json:"granted_by,omitempty"`
+	DeniedBy        *DenyRule         `json:"denied_by,omitempty"`
+	UnmetConditions []PermissionGrant `json:"unmet_conditions,omitempty"`
+}
+
+type EffectivePermissions struct {
+	AppID       string            `json:"app_id"`
+	UserID      string            `json:"user_id"`
+	Resource    string            `json:"resource,omitempty"`
+	Roles       []string          `json:"roles"`
+	Permissions []string          `json:"permissions"`
+	Deny        []string          `json:"deny,omitempty"`
+	Conditional []string          `json:"conditional,omitempty"`
+	Grants      []PermissionGrant `json:"grants,omitempty"`
+	DenyRules   []DenyRule        `json:"deny_rules,omitempty"`
+}
+
+// Group roles are held app-wide and without expiry.
+type Group struct {
 	AppID       string    `json:"app_id"`
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
-	Permissions []string  `json:"permissions"`
+	Description string    `json:"description"`
+	Roles       []string  `json:"roles"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+type GroupMember struct {
+	AppID   string    `json:"app_id"`
+	GroupID string    `json:"group_id"`
+	UserID  string    `json:"user_id"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type ClaimMapping struct {
+	AppID     string    `json:"app_id"`
+	ID        string    `json:"id"`
+	Claim     string    `json:"claim"`
+	Value     string    `json:"value"`
+	RoleID    string    `json:"role_id"`
+	Sync      bool      `json:"sync"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Matches also accepts list claims, like cognito:groups, holding the value.
+func (m ClaimMapping) Matches(claims map[string]any) bool {
+	switch v := claims[m.Claim].(type) {
+	case string:
+		return v == m.Value
+	case []string:
+		return slices.Contains(v, m.Value)
+	case []any:
+		return slices.ContainsFunc(v, func(e any) bool {
+			str, ok := e.(string)
+			return ok && str == m.Value
+		})
+	default:
+		return false
+	}
+}
+
+const APIKeyAllApps = "*"
+
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Principal string     `json:"principal"`
+	AppIDs    []string   `json:"app_ids"`
+	Hash      string     `json:"-"`
+	Revoked   bool       `json:"revoked"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k APIKey) AllowsAllApps() bool {
+	return slices.Contains(k.AppIDs, APIKeyAllApps)
+}
+
+func (k APIKey) AllowsApp(appID string) bool {
+	return k.AllowsAllApps() || slices.Contains(k.AppIDs, appID)
+}
+
+func (k APIKey) Usable(t time.Time) bool {
+	return !k.Revoked && t.Before(k.ExpiresAt)
+}
+
+type IssuedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
+
 type Permission struct {
 	AppID       string    `json:"app_id"`
 	ID          string    `json:"id"`
@@ -28,8 +218,188 @@ type Permission struct {
 }
 
 type UserAppRoles struct {
-	UserID    string    `json:"user_id"`
-	AppID     string    `json:"app_id"`
-	Roles     []string  `json:"roles"`
-	UpdatedAt time.Time `json:"updated_at"`
+	UserID    string      `json:"user_id"`
+	AppID     string      `json:"app_id"`
+	Roles     []string    `json:"roles"`
+	Grants    []RoleGrant `json:"grants"`
+	Groups    []string    `json:"groups,omitempty"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ActiveRoles with an empty resource only counts app-wide grants.
+func (u UserAppRoles) ActiveRoles(t time.Time, resource string) []string {
+	active := make([]string, 0, len(u.Roles))
+	for _, roleID := range u.Roles {
+		for _, grant := range u.grantsOf(roleID) {
+			if grant.ActiveAt(t) && grant.Covers(resource) {
+				active = append(active, roleID)
+				break
+			}
+		}
+	}
+	return active
+}
+
+func (u UserAppRoles) HeldRoles(t time.Time) []string {
+	held := make([]string, 0, len(u.Roles))
+	for _, roleID := range u.Roles {
+		if slices.ContainsFunc(u.grantsOf(roleID), func(grant RoleGrant) bool { return !grant.ExpiredAt(t) }) {
+			held = append(held, roleID)
+		}
+	}
+	return held
+}
+
+func (u UserAppRoles) InactiveGrants(t time.Time, resource string) []RoleGrant {
+	var inactive []RoleGrant
+	for _, roleID := range u.Roles {
+		for _, grant := range u.grantsOf(roleID) {
+			if !grant.ActiveAt(t) || !grant.Covers(resource) {
+				inactive = append(inactive, grant)
+			}
+		}
+	}
+	return inactive
+}
+
+func (u UserAppRoles) HasAppWideGrant(roleID string) bool {
+	if !slices.Contains(u.Roles, roleID) {
+		return false
+	}
+	return slices.ContainsFunc(u.grantsOf(roleID), func(grant RoleGrant) bool { return grant.Scope == "" })
+}
+
+// WithoutGrants drops the grants matching drop, and roles left without one.
+func (u UserAppRoles) WithoutGrants(drop func(RoleGrant) bool) UserAppRoles {
+	grants := make([]RoleGrant, 0, len(u.Grants))
+	roles := make([]string, 0, len(u.Roles))
+	for _, roleID := range u.Roles {
+		kept := slices.DeleteFunc(u.grantsOf(roleID), drop)
+		if len(kept) > 0 {
+			roles = append(roles, roleID)
+			grants = append(grants, kept...)
+		}
+	}
+	u.Roles, u.Grants = roles, grants
+	return u
+}
+
+// Roles assigned before grants were recorded are held app-wide and permanently.
+func (u UserAppRoles) grantsOf(roleID string) []RoleGrant {
+	var grants []RoleGrant
+	for _, grant := range u.Grants {
+		if grant.RoleID == roleID {
+			grants = append(grants, grant)
+		}
+	}
+	if len(grants) == 0 {
+		return []RoleGrant{{RoleID: roleID}}
+	}
+	return grants
+}
+
+// An empty RoleGrant.Scope means app-wide. Source is set on grants written by
+// a claim mapping sync.
+type RoleGrant struct {
+	RoleID    string     `json:"role_id"`
+	Scope     string     `json:"scope,omitempty"`
+	StartsAt  *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Source    string     `json:"source,omitempty"`
+}
+
+const claimMappingSourcePrefix = "claim-mapping:"
+
+func ClaimMappingSource(mappingID string) string { return claimMappingSourcePrefix + mappingID }
+
+// SyncedBy returns the claim mapping that synced the grant, if any.
+func (g RoleGrant) SyncedBy() (string, bool) {
+	return strings.CutPrefix(g.Source, claimMappingSourcePrefix)
+}
+
+func (g RoleGrant) ActiveAt(t time.Time) bool {
+	if g.StartsAt != nil && t.Before(*g.StartsAt) {
+		return false
+	}
+	return !g.ExpiredAt(t)
+}
+
+func (g RoleGrant) ExpiredAt(t time.Time) bool {
+	return g.ExpiresAt != nil && !t.Before(*g.ExpiresAt)
+}
+
+func (g RoleGrant) Covers(resource string) bool {
+	return ScopeCovers(g.Scope, resource)
+}
+
+type UserApplicationAccess struct {
+	AppID       string    `json:"app_id"`
+	Roles       []string  `json:"roles"`
+	Permissions []string  `json:"permissions,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RoleMember struct {
+	AppID      string     `json:"app_id"`
+	RoleID     string     `json:"role_id"`
+	UserID     string     `json:"user_id"`
+	Scope      string     `json:"scope,omitempty"`
+	AssignedAt time.Time  `json:"assigned_at"`
+	StartsAt   *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Source     string     `json:"source,omitempty"`
+}
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 100
+)
+
+// MaxGroupsPerUser keeps a user's groups within one BatchGetItem.
+const MaxGroupsPerUser = 100
+
+const MaxAuthorizeBatch = 100
+
+type PageRequest struct {
+	Limit     int
+	NextToken string
+}
+
+type Page[T any] struct {
+	Items     []T    `json:"items"`
+	NextToken string `json:"next_token,omitempty"`
+}
+
+type ApplicationQuery struct {
+	NamePrefix string
+	Descending bool
+	Page       PageRequest
+}
+
+type ApplicationDeletion struct {
+	AppID              string `json:"app_id"`
+	RolesRemoved       int    `json:"roles_removed"`
+	PermissionsRemoved int    `json:"permissions_removed"`
+	AssignmentsRemoved int    `json:"assignments_removed"`
+	GroupsRemoved      int    `json:"groups_removed"`
+	MappingsRemoved    int    `json:"claim_mappings_removed"`
+	Completed          bool   `json:"completed"`
+	NextToken          string `json:"next_token,omitempty"`
+}
+
+type RoleDeletion struct {
+	AppID         string   `json:"app_id"`
+	RoleID        string   `json:"role_id"`
+	DryRun        bool     `json:"dry_run"`
+	AffectedUsers int      `json:"affected_users"`
+	UserIDs       []string `json:"user_ids"`
+	ChildRoles    []string `json:"child_roles"`
+	Groups        []string `json:"groups"`
+	ClaimMappings []string `json:"claim_mappings"`
+}
+
+type PermissionDeletion struct {
+	AppID        string   `json:"app_id"`
+	PermissionID string   `json:"permission_id"`
+	RolesUpdated []string `json:"roles_updated"`
 }
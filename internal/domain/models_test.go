@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClaimMappingMatches(t *testing.T) {
+	mapping := ClaimMapping{Claim: "groups", Value: "writers"}
+	tests := []struct {
+		claims map[string]any
+		want   bool
+	}{
+		{map[string]any{"groups": "writers"}, true},
+		{map[string]any{"groups": "readers"}, false},
+		{map[string]any{"groups": []any{"readers", "writers"}}, true},
+		{map[string]any{"groups": []any{map[string]any{"name": "writers"}}}, false},
+		{map[string]any{"groups": []string{"writers"}}, true},
+		{map[string]any{"roles": "writers"}, false},
+		{map[string]any{"groups": 1.0}, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, mapping.Matches(tt.claims), "%v", tt.claims)
+	}
+}
+
+func TestUserAppRolesHasAppWideGrant(t *testing.T) {
+	user := UserAppRoles{
+		Roles:  []string{"viewer", "editor", "legacy"},
+		Grants: []RoleGrant{{RoleID: "viewer", Scope: "org/acme"}, {RoleID: "editor"}},
+	}
+	assert.False(t, user.HasAppWideGrant("viewer"))
+	assert.True(t, user.HasAppWideGrant("editor"))
+	assert.True(t, user.HasAppWideGrant("legacy"))
+	assert.False(t, user.HasAppWideGrant("admin"))
+}
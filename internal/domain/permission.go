@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// The permission grammar is described in the README.
+const (
+	PermissionSeparator = ":"
+	PermissionWildcard  = "*"
+)
+
+func ValidatePermissionPattern(pattern string) error {
+	return validatePermission(pattern, true)
+}
+
+func ValidatePermission(permission string) error {
+	return validatePermission(permission, false)
+}
+
+func validatePermission(value string, allowWildcard bool) error {
+	if value == "" {
+		return fmt.Errorf("%w: empty permission", ErrInvalidInput)
+	}
+	for _, segment := range strings.Split(value, PermissionSeparator) {
+		switch {
+		case segment == "":
+			return fmt.Errorf("%w: permission %q has an empty segment", ErrInvalidInput, value)
+		case segment == PermissionWildcard && !allowWildcard:
+			return fmt.Errorf("%w: permission %q must not contain wildcards", ErrInvalidInput, value)
+		case segment != PermissionWildcard && strings.Contains(segment, PermissionWildcard):
+			return fmt.Errorf("%w: permission %q may only use %q as a whole segment", ErrInvalidInput, value, PermissionWildcard)
+		case strings.IndexFunc(segment, unicode.IsSpace) >= 0:
+			return fmt.Errorf("%w: permission %q contains whitespace", ErrInvalidInput, value)
+		}
+	}
+	return nil
+}
+
+func MatchPermission(pattern, permission string) bool {
+	if pattern == permission {
+		return true
+	}
+	want := strings.Split(pattern, PermissionSeparator)
+	got := strings.Split(permission, PermissionSeparator)
+	for i, segment := range want {
+		if i == len(got) {
+			return false
+		}
+		if segment == PermissionWildcard {
+			if i == len(want)-1 {
+				return true
+			}
+			continue
+		}
+		if segment != got[i] {
+			return false
+		}
+	}
+	return len(want) == len(got)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		permission string
+		want       bool
+	}{
+		{"documents:read", "documents:read", true},
+		{"documents:read", "documents:write", false},
+		{"documents:read", "documents:read:own", false},
+		{"documents:read:own", "documents:read", false},
+		{"*", "documents", true},
+		{"*", "documents:read:own", true},
+		{"documents:*", "documents:read", true},
+		{"documents:*", "documents:read:own", true},
+		{"documents:*", "documents", false},
+		{"documents:*", "invoices:read", false},
+		{"documents:*:own", "documents:read:own", true},
+		{"documents:*:own", "documents:read:all", false},
+		{"documents:*:own", "documents:read:all:own", false},
+		{"documents:*:own", "documents:read", false},
+		{"*:read", "invoices:read", true},
+		{"*:read", "invoices:read:own", false},
+		{"Documents:read", "documents:read", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"~"+tt.permission, func(t *testing.T) {
+			assert.Equal(t, tt.want, MatchPermission(tt.pattern, tt.permission))
+		})
+	}
+}
+
+func TestValidatePermissionPattern(t *testing.T) {
+	tests := []struct {
+		pattern      string
+		validPattern bool
+		concrete     bool
+	}{
+		{"documents:read", true, true},
+		{"read", true, true},
+		{"*", true, false},
+		{"documents:*", true, false},
+		{"documents:*:own", true, false},
+		{"", false, false},
+		{"documents:", false, false},
+		{":read", false, false},
+		{"documents::read", false, false},
+		{"documents:re*d", false, false},
+		{"documents:**", false, false},
+		{"documents:read all", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if tt.validPattern {
+				assert.NoError(t, ValidatePermissionPattern(tt.pattern))
+			} else {
+				assert.ErrorIs(t, ValidatePermissionPattern(tt.pattern), ErrInvalidInput)
+			}
+			if tt.concrete {
+				assert.NoError(t, ValidatePermission(tt.pattern))
+			} else {
+				assert.ErrorIs(t, ValidatePermission(tt.pattern), ErrInvalidInput)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Scopes are described in the README.
+const ScopeSeparator = "/"
+
+func ValidateScope(scope string) error {
+	if scope == "" {
+		return fmt.Errorf("%w: empty resource scope", ErrInvalidInput)
+	}
+	for _, segment := range strings.Split(scope, ScopeSeparator) {
+		if segment == "" {
+			return fmt.Errorf("%w: resource scope %q has an empty segment", ErrInvalidInput, scope)
+		}
+		if strings.IndexFunc(segment, unicode.IsSpace) >= 0 {
+			return fmt.Errorf("%w: resource scope %q contains whitespace", ErrInvalidInput, scope)
+		}
+	}
+	return nil
+}
+
+// An empty resource is only covered by app-wide grants.
+func ScopeCovers(scope, resource string) bool {
+	if scope == "" {
+		return true
+	}
+	return resource == scope || strings.HasPrefix(resource, scope+ScopeSeparator)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeCovers(t *testing.T) {
+	tests := []struct {
+		scope    string
+		resource string
+		want     bool
+	}{
+		{"", "", true},
+		{"", "org/acme", true},
+		{"org/acme", "org/acme", true},
+		{"org/acme", "org/acme/project/42", true},
+		{"org/acme", "org/acme-labs", false},
+		{"org/acme", "org", false},
+		{"org/acme", "", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ScopeCovers(tt.scope, tt.resource), "%q covers %q", tt.scope, tt.resource)
+	}
+}
+
+func TestValidateScope(t *testing.T) {
+	assert.NoError(t, ValidateScope("org/acme/project/42"))
+	for _, scope := range []string{"", "/org", "org/", "org//acme", "org/ac me"} {
+		assert.ErrorIs(t, ValidateScope(scope), ErrInvalidInput, scope)
+	}
+}
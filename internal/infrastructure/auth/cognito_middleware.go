@@ -8,6 +8,7 @@ import (
 	"math/big"
 	"net/http"
 	"rbac-project/internal/domain"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -121,43 +122,77 @@ func rsaFromJWK(nB64, eB64 string) (*rsa.PublicKey, error) {
 	return &rsa.PublicKey{N: new(big.Int).SetBytes(nRaw), E: eInt}, nil
 }
 
+const (
+	TokenUseAccess = "access"
+	TokenUseID     = "id"
+)
+
+// CognitoConfig.TokenUses defaults to access tokens only.
+type CognitoConfig struct {
+	UserPoolID string
+	Region     string
+	ClientIDs  []string
+	TokenUses  []string
+}
+
 type CognitoMiddleware struct {
-	userPoolID string
-	region     string
-	cache      *jwkCache
+	issuer    string
+	clientIDs []string
+	tokenUses []string
+	cache     *jwkCache
 }
 
-func NewCognitoMiddleware(userPoolID, region string) *CognitoMiddleware {
-	jwksURL := "https://cognito-idp." + region + ".amazonaws.com/" + userPoolID + "/.well-known/jwks.json"
+func NewCognitoMiddleware(cfg CognitoConfig) *CognitoMiddleware {
+	issuer := "https://cognito-idp." + cfg.Region + ".amazonaws.com/" + cfg.UserPoolID
+	return newCognitoMiddleware(cfg, issuer, issuer+"/.well-known/jwks.json")
+}
+
+func newCognitoMiddleware(cfg CognitoConfig, issuer, jwksURL string) *CognitoMiddleware {
+	tokenUses := cfg.TokenUses
+	if len(tokenUses) == 0 {
+		tokenUses = []string{TokenUseAccess}
+	}
 	return &CognitoMiddleware{
-		userPoolID: userPoolID,
-		region:     region,
-		cache:      newJWKCache(jwksURL, 15*time.Minute),
+		issuer:    issuer,
+		clientIDs: cfg.ClientIDs,
+		tokenUses: tokenUses,
+		cache:     newJWKCache(jwksURL, 15*time.Minute),
+	}
+}
+
+func (m *CognitoMiddleware) validateClaims(claims jwt.MapClaims) error {
+	tokenUse, _ := claims["token_use"].(string)
+	if !slices.Contains(m.tokenUses, tokenUse) {
+		return errors.New("unexpected token use")
+	}
+	if len(m.clientIDs) == 0 {
+		return nil
+	}
+	var clients []string
+	if tokenUse == TokenUseAccess {
+		clientID, _ := claims["client_id"].(string)
+		clients = []string{clientID}
+	} else {
+		aud, err := claims.GetAudience()
+		if err != nil {
+			return err
+		}
+		clients = aud
+	}
+	if !slices.ContainsFunc(clients, func(client string) bool { return slices.Contains(m.clientIDs, client) }) {
+		return errors.New("unexpected client")
 	}
+	return nil
 }
 
 func (m *CognitoMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		authHeader := c.Request().Header.Get("Authorization")
-		if authHeader == "" {
+		tokenString, ok := bearerToken(c)
+		if !ok {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing authorization token"})
 		}
-		tokenString := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer"))
-		if tokenString == "" {
-			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid authorization token"})
-		}
-		claims := jwt.MapClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
-				return nil, errors.New("unexpected signing method")
-			}
-			kid, ok := token.Header["kid"].(string)
-			if !ok || kid == "" {
-				return nil, errors.New("missing kid")
-			}
-			return m.cache.keyForKid(kid)
-		}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}))
-		if err != nil || !token.Valid {
+		claims, err := verifyToken(tokenString, m.cache, m.issuer)
+		if err != nil || m.validateClaims(claims) != nil {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
 		}
 		sub, _ := claims["sub"].(string)
@@ -165,6 +200,30 @@ func (m *CognitoMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": domain.ErrInvalidInput.Error()})
 		}
 		c.Set("user_id", sub)
+		c.Set("claims", map[string]any(claims))
 		return next(c)
 	}
 }
+
+func bearerToken(c echo.Context) (string, bool) {
+	tokenString := strings.TrimSpace(strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer"))
+	return tokenString, tokenString != ""
+}
+
+func verifyToken(tokenString string, cache *jwkCache, issuer string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			return nil, errors.New("missing kid")
+		}
+		return cache.keyForKid(kid)
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg()}), jwt.WithIssuer(issuer), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
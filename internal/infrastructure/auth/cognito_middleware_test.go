@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIssuer = "https://cognito-idp.eu-west-1.amazonaws.com/eu-west-1_pool"
+
+// stubJWKS serves the public half of key under kid.
+func stubJWKS(t *testing.T, kid string, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(jwksResponse{Keys: []jwk{{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func signToken(t *testing.T, kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func accessClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       testIssuer,
+		"sub":       "u1",
+		"token_use": TokenUseAccess,
+		"client_id": "web",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func idClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":       testIssuer,
+		"sub":       "u1",
+		"token_use": TokenUseID,
+		"aud":       "web",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func with(claims jwt.MapClaims, key string, value any) jwt.MapClaims {
+	claims[key] = value
+	return claims
+}
+
+func without(claims jwt.MapClaims, key string) jwt.MapClaims {
+	delete(claims, key)
+	return claims
+}
+
+func TestCognitoMiddlewareHandler(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	srv := stubJWKS(t, "kid-1", key)
+
+	cases := []struct {
+		name   string
+		cfg    CognitoConfig
+		token  string
+		status int
+	}{
+		{"access token", CognitoConfig{ClientIDs: []string{"web"}}, signToken(t, "kid-1", key, accessClaims()), http.StatusOK},
+		{"no client allow-list", CognitoConfig{}, signToken(t, "kid-1", key, with(accessClaims(), "client_id", "cli")), http.StatusOK},
+		{"id token when accepted", CognitoConfig{ClientIDs: []string{"web"}, TokenUses: []string{TokenUseAccess, TokenUseID}}, signToken(t, "kid-1", key, idClaims()), http.StatusOK},
+		{"id token by default", CognitoConfig{ClientIDs: []string{"web"}}, signToken(t, "kid-1", key, idClaims()), http.StatusUnauthorized},
+		{"access token when only id accepted", CognitoConfig{TokenUses: []string{TokenUseID}}, signToken(t, "kid-1", key, accessClaims()), http.StatusUnauthorized},
+		{"missing token use", CognitoConfig{}, signToken(t, "kid-1", key, without(accessClaims(), "token_use")), http.StatusUnauthorized},
+		{"other pool", CognitoConfig{}, signToken(t, "kid-1", key, with(accessClaims(), "iss", "https://cognito-idp.eu-west-1.amazonaws.com/other")), http.StatusUnauthorized},
+		{"missing issuer", CognitoConfig{}, signToken(t, "kid-1", key, without(accessClaims(), "iss")), http.StatusUnauthorized},
+		{"client not allowed", CognitoConfig{ClientIDs: []string{"web"}}, signToken(t, "kid-1", key, with(accessClaims(), "client_id", "cli")), http.StatusUnauthorized},
+		{"audience not allowed", CognitoConfig{ClientIDs: []string{"web"}, TokenUses: []string{TokenUseID}}, signToken(t, "kid-1", key, with(idClaims(), "aud", "cli")), http.StatusUnauthorized},
+		{"expired", CognitoConfig{}, signToken(t, "kid-1", key, with(accessClaims(), "exp", time.Now().Add(-time.Minute).Unix())), http.StatusUnauthorized},
+		{"missing expiry", CognitoConfig{}, signToken(t, "kid-1", key, without(accessClaims(), "exp")), http.StatusUnauthorized},
+		{"unknown kid", CognitoConfig{}, signToken(t, "kid-2", key, accessClaims()), http.StatusUnauthorized},
+		{"wrong signing key", CognitoConfig{}, signToken(t, "kid-1", otherKey, accessClaims()), http.StatusUnauthorized},
+		{"missing subject", CognitoConfig{}, signToken(t, "kid-1", key, without(accessClaims(), "sub")), http.StatusUnauthorized},
+		{"missing token", CognitoConfig{}, "", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newCognitoMiddleware(tc.cfg, testIssuer, srv.URL)
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, c.Get("user_id").(string))
+			}, m.Handler)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, "u1", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewCognitoMiddlewareDerivesIssuer(t *testing.T) {
+	m := NewCognitoMiddleware(CognitoConfig{UserPoolID: "eu-west-1_pool", Region: "eu-west-1"})
+	assert.Equal(t, testIssuer, m.issuer)
+	assert.Equal(t, testIssuer+"/.well-known/jwks.json", m.cache.url)
+	assert.Equal(t, []string{TokenUseAccess}, m.tokenUses)
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const DefaultUserIDClaim = "sub"
+
+type OIDCConfig struct {
+	IssuerURL   string
+	Audiences   []string
+	UserIDClaim string
+}
+
+type discoveryDocument struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+type OIDCMiddleware struct {
+	issuer      string
+	audiences   []string
+	userIDClaim string
+	cache       *jwkCache
+}
+
+func NewOIDCMiddleware(ctx context.Context, cfg OIDCConfig) (*OIDCMiddleware, error) {
+	if cfg.IssuerURL == "" || len(cfg.Audiences) == 0 {
+		return nil, errors.New("oidc issuer and audience are required")
+	}
+	doc, err := discover(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Issuer != cfg.IssuerURL {
+		return nil, errors.New("oidc discovery document names another issuer")
+	}
+	if doc.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document has no jwks_uri")
+	}
+	userIDClaim := cfg.UserIDClaim
+	if userIDClaim == "" {
+		userIDClaim = DefaultUserIDClaim
+	}
+	return &OIDCMiddleware{
+		issuer:      cfg.IssuerURL,
+		audiences:   cfg.Audiences,
+		userIDClaim: userIDClaim,
+		cache:       newJWKCache(doc.JWKSURI, 15*time.Minute),
+	}, nil
+}
+
+func discover(ctx context.Context, issuer string) (discoveryDocument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return discoveryDocument{}, err
+	}
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+	if err != nil {
+		return discoveryDocument{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return discoveryDocument{}, errors.New("unable to fetch oidc discovery document")
+	}
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return discoveryDocument{}, err
+	}
+	return doc, nil
+}
+
+func (m *OIDCMiddleware) validateAudience(claims jwt.MapClaims) error {
+	aud, err := claims.GetAudience()
+	if err != nil {
+		return err
+	}
+	if !slices.ContainsFunc(aud, func(a string) bool { return slices.Contains(m.audiences, a) }) {
+		return errors.New("unexpected audience")
+	}
+	return nil
+}
+
+func (m *OIDCMiddleware) Handler(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		tokenString, ok := bearerToken(c)
+		if !ok {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing authorization token"})
+		}
+		claims, err := verifyToken(tokenString, m.cache, m.issuer)
+		if err != nil || m.validateAudience(claims) != nil {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "invalid token"})
+		}
+		userID, _ := claims[m.userIDClaim].(string)
+		if userID == "" {
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "missing " + m.userIDClaim + " claim"})
+		}
+		c.Set("user_id", userID)
+		c.Set("claims", map[string]any(claims))
+		return next(c)
+	}
+}
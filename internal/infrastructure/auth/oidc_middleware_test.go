@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP serves a discovery document naming issuer, or the server itself
+// when issuer is empty, and a JWKS with the public half of key under kid.
+func stubIdP(t *testing.T, issuer, kid string, key *rsa.PrivateKey) *httptest.Server {
+	t.Helper()
+	jwks := stubJWKS(t, kid, key)
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	if issuer == "" {
+		issuer = srv.URL
+	}
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(discoveryDocument{Issuer: issuer, JWKSURI: jwks.URL})
+	})
+	return srv
+}
+
+func oidcClaims(issuer string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   issuer,
+		"sub":   "00u1",
+		"email": "ada@example.com",
+		"aud":   []string{"rbac"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDCMiddlewareHandler(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := stubIdP(t, "", "kid-1", key)
+
+	cases := []struct {
+		name   string
+		cfg    OIDCConfig
+		token  string
+		status int
+		userID string
+	}{
+		{"sub by default", OIDCConfig{Audiences: []string{"rbac"}}, signToken(t, "kid-1", key, oidcClaims(idp.URL)), http.StatusOK, "00u1"},
+		{"configured claim", OIDCConfig{Audiences: []string{"rbac"}, UserIDClaim: "email"}, signToken(t, "kid-1", key, oidcClaims(idp.URL)), http.StatusOK, "ada@example.com"},
+		{"one of several audiences", OIDCConfig{Audiences: []string{"other", "rbac"}}, signToken(t, "kid-1", key, oidcClaims(idp.URL)), http.StatusOK, "00u1"},
+		{"missing configured claim", OIDCConfig{Audiences: []string{"rbac"}, UserIDClaim: "oid"}, signToken(t, "kid-1", key, oidcClaims(idp.URL)), http.StatusUnauthorized, ""},
+		{"audience not allowed", OIDCConfig{Audiences: []string{"other"}}, signToken(t, "kid-1", key, oidcClaims(idp.URL)), http.StatusUnauthorized, ""},
+		{"missing audience", OIDCConfig{Audiences: []string{"rbac"}}, signToken(t, "kid-1", key, without(oidcClaims(idp.URL), "aud")), http.StatusUnauthorized, ""},
+		{"other issuer", OIDCConfig{Audiences: []string{"rbac"}}, signToken(t, "kid-1", key, oidcClaims("https://idp.example.com")), http.StatusUnauthorized, ""},
+		{"expired", OIDCConfig{Audiences: []string{"rbac"}}, signToken(t, "kid-1", key, with(oidcClaims(idp.URL), "exp", time.Now().Add(-time.Minute).Unix())), http.StatusUnauthorized, ""},
+		{"unknown kid", OIDCConfig{Audiences: []string{"rbac"}}, signToken(t, "kid-2", key, oidcClaims(idp.URL)), http.StatusUnauthorized, ""},
+		{"missing token", OIDCConfig{Audiences: []string{"rbac"}}, "", http.StatusUnauthorized, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.IssuerURL = idp.URL
+			m, err := NewOIDCMiddleware(context.Background(), tc.cfg)
+			require.NoError(t, err)
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				return c.String(http.StatusOK, c.Get("user_id").(string))
+			}, m.Handler)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tc.status, rec.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, tc.userID, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewOIDCMiddlewareRejectsBadConfig(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := stubIdP(t, "", "kid-1", key)
+	mismatched := stubIdP(t, "https://idp.example.com", "kid-1", key)
+	missing := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(missing.Close)
+
+	cases := []struct {
+		name string
+		cfg  OIDCConfig
+	}{
+		{"no issuer", OIDCConfig{Audiences: []string{"rbac"}}},
+		{"no audience", OIDCConfig{IssuerURL: idp.URL}},
+		{"issuer mismatch", OIDCConfig{IssuerURL: mismatched.URL, Audiences: []string{"rbac"}}},
+		{"no discovery document", OIDCConfig{IssuerURL: missing.URL, Audiences: []string{"rbac"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := NewOIDCMiddleware(context.Background(), tc.cfg)
+			assert.Nil(t, m)
+			assert.Error(t, err)
+		})
+	}
+}
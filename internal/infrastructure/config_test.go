@@ -174,3 +174,50 @@ func TestInfraYAMLStructureForIAMAndECSWiring(t *testing.T) {
 		t.Fatal("ecs compose dependencies missing iam")
 	}
 }
+
+func TestDynamoDBTableIndexes(t *testing.T) {
+	root := parseYAML(t, "infrastructure/dynamodb/serverless.yml")
+	resources := mappingValue(t, mappingValue(t, root, "resources"), "Resources")
+	props := mappingValue(t, mappingValue(t, resources, "RBACDynamoTable"), "Properties")
+	indexes := mappingValue(t, props, "GlobalSecondaryIndexes")
+
+	want := map[string][2]string{
+		"InvertedIndex":   {"SK", "PK"},
+		"EntityTypeIndex": {"EntityType", "CreatedAt"},
+	}
+	for _, index := range indexes.Content {
+		name := mappingValue(t, index, "IndexName").Value
+		keys, ok := want[name]
+		if !ok {
+			continue
+		}
+		delete(want, name)
+		keySchema := mappingValue(t, index, "KeySchema")
+		if got := mappingValue(t, keySchema.Content[0], "AttributeName").Value; got != keys[0] {
+			t.Fatalf("unexpected %s hash key: %q", name, got)
+		}
+		if got := mappingValue(t, keySchema.Content[1], "AttributeName").Value; got != keys[1] {
+			t.Fatalf("unexpected %s range key: %q", name, got)
+		}
+	}
+	for name := range want {
+		t.Fatalf("dynamodb table missing %s", name)
+	}
+
+	const policies = "infrastructure/iam/policies.yml"
+	assertContains(t, readFixture(t, policies), "/index/*", policies)
+}
+
+func TestDynamoDBTableTTL(t *testing.T) {
+	root := parseYAML(t, "infrastructure/dynamodb/serverless.yml")
+	resources := mappingValue(t, mappingValue(t, root, "resources"), "Resources")
+	props := mappingValue(t, mappingValue(t, resources, "RBACDynamoTable"), "Properties")
+	ttl := mappingValue(t, props, "TimeToLiveSpecification")
+
+	if got := mappingValue(t, ttl, "AttributeName").Value; got != "TTL" {
+		t.Fatalf("unexpected ttl attribute: %q", got)
+	}
+	if got := mappingValue(t, ttl, "Enabled").Value; got != "true" {
+		t.Fatalf("ttl not enabled: %q", got)
+	}
+}
@@ -0,0 +1,149 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/domain"
+)
+
+func apiKeyPK(keyID string) string { return "APIKEY#" + keyID }
+
+type APIKeyRepository struct{ client *Client }
+
+func NewAPIKeyRepository(client *Client) *APIKeyRepository {
+	return &APIKeyRepository{client: client}
+}
+
+func apiKeyKey(keyID string) map[string]awsv2types.AttributeValue {
+	return map[string]awsv2types.AttributeValue{
+		"PK": &awsv2types.AttributeValueMemberS{Value: apiKeyPK(keyID)},
+		"SK": &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
+	}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, key domain.APIKey) error {
+	item := map[string]awsv2types.AttributeValue{
+		"PK":         &awsv2types.AttributeValueMemberS{Value: apiKeyPK(key.ID)},
+		"SK":         &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
+		"EntityType": &awsv2types.AttributeValueMemberS{Value: "API_KEY"},
+		"ID":         &awsv2types.AttributeValueMemberS{Value: key.ID},
+		"Name":       &awsv2types.AttributeValueMemberS{Value: key.Name},
+		"Principal":  &awsv2types.AttributeValueMemberS{Value: key.Principal},
+		"AppIDs":     &awsv2types.AttributeValueMemberSS{Value: key.AppIDs},
+		"KeyHash":    &awsv2types.AttributeValueMemberS{Value: key.Hash},
+		"Revoked":    &awsv2types.AttributeValueMemberBOOL{Value: false},
+		"CreatedAt":  &awsv2types.AttributeValueMemberS{Value: key.CreatedAt.Format(time.RFC3339)},
+		"ExpiresAt":  &awsv2types.AttributeValueMemberS{Value: key.ExpiresAt.Format(time.RFC3339)},
+	}
+	return xray.Capture(ctx, "DynamoDB.PutAPIKey", func(ctx context.Context) error {
+		_, err := r.client.db.PutItem(ctx, &awsv2dynamodb.PutItemInput{
+			TableName:           aws.String(r.client.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrConflict
+		}
+		return err
+	})
+}
+
+func (r *APIKeyRepository) Get(ctx context.Context, keyID string) (domain.APIKey, error) {
+	var out *awsv2dynamodb.GetItemOutput
+	err := xray.Capture(ctx, "DynamoDB.GetAPIKey", func(ctx context.Context) error {
+		var e error
+		out, e = r.client.db.GetItem(ctx, &awsv2dynamodb.GetItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key:       apiKeyKey(keyID),
+		})
+		return e
+	})
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	if out.Item == nil {
+		return domain.APIKey{}, domain.ErrNotFound
+	}
+	return apiKeyFromItem(out.Item)
+}
+
+func (r *APIKeyRepository) List(ctx context.Context, page domain.PageRequest) (domain.Page[domain.APIKey], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryAPIKeys", &awsv2dynamodb.QueryInput{
+		IndexName:              aws.String(entityTypeIndex),
+		KeyConditionExpression: aws.String("EntityType = :t"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":t": &awsv2types.AttributeValueMemberS{Value: "API_KEY"},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.APIKey]{}, err
+	}
+	keys := make([]domain.APIKey, 0, len(items))
+	for _, item := range items {
+		key, err := apiKeyFromItem(item)
+		if err != nil {
+			return domain.Page[domain.APIKey]{}, err
+		}
+		keys = append(keys, key)
+	}
+	return domain.Page[domain.APIKey]{Items: keys, NextToken: next}, nil
+}
+
+// Revoking again keeps the first RevokedAt.
+func (r *APIKeyRepository) Revoke(ctx context.Context, keyID string, at time.Time) error {
+	return xray.Capture(ctx, "DynamoDB.RevokeAPIKey", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName:        aws.String(r.client.tableName),
+			Key:              apiKeyKey(keyID),
+			UpdateExpression: aws.String("SET Revoked = :r, RevokedAt = if_not_exists(RevokedAt, :at)"),
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":r":  &awsv2types.AttributeValueMemberBOOL{Value: true},
+				":at": &awsv2types.AttributeValueMemberS{Value: at.UTC().Format(time.RFC3339)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func apiKeyFromItem(item map[string]awsv2types.AttributeValue) (domain.APIKey, error) {
+	raw := struct {
+		ID        string   `dynamodbav:"ID"`
+		Name      string   `dynamodbav:"Name"`
+		Principal string   `dynamodbav:"Principal"`
+		AppIDs    []string `dynamodbav:"AppIDs,stringset"`
+		KeyHash   string   `dynamodbav:"KeyHash"`
+		Revoked   bool     `dynamodbav:"Revoked"`
+		CreatedAt string   `dynamodbav:"CreatedAt"`
+		ExpiresAt string   `dynamodbav:"ExpiresAt"`
+		RevokedAt string   `dynamodbav:"RevokedAt"`
+	}{}
+	if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
+		return domain.APIKey{}, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, raw.CreatedAt)
+	expiresAt, _ := time.Parse(time.RFC3339, raw.ExpiresAt)
+	key := domain.APIKey{
+		ID:        raw.ID,
+		Name:      raw.Name,
+		Principal: raw.Principal,
+		AppIDs:    raw.AppIDs,
+		Hash:      raw.KeyHash,
+		Revoked:   raw.Revoked,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	}
+	if revokedAt, err := time.Parse(time.RFC3339, raw.RevokedAt); err == nil {
+		key.RevokedAt = &revokedAt
+	}
+	return key, nil
+}
@@ -0,0 +1,182 @@
+package dynamodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/domain"
+)
+
+func claimMappingSK(mappingID string) string { return "CLAIMMAP#" + mappingID }
+
+type ClaimMappingRepository struct{ client *Client }
+
+func NewClaimMappingRepository(client *Client) *ClaimMappingRepository {
+	return &ClaimMappingRepository{client: client}
+}
+
+func claimMappingKey(appID, mappingID string) map[string]awsv2types.AttributeValue {
+	return map[string]awsv2types.AttributeValue{
+		"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+		"SK": &awsv2types.AttributeValueMemberS{Value: claimMappingSK(mappingID)},
+	}
+}
+
+func (r *ClaimMappingRepository) Create(ctx context.Context, mapping domain.ClaimMapping) error {
+	item := map[string]awsv2types.AttributeValue{
+		"PK":         &awsv2types.AttributeValueMemberS{Value: appPK(mapping.AppID)},
+		"SK":         &awsv2types.AttributeValueMemberS{Value: claimMappingSK(mapping.ID)},
+		"EntityType": &awsv2types.AttributeValueMemberS{Value: "CLAIM_MAPPING"},
+		"ID":         &awsv2types.AttributeValueMemberS{Value: mapping.ID},
+		"Claim":      &awsv2types.AttributeValueMemberS{Value: mapping.Claim},
+		"Value":      &awsv2types.AttributeValueMemberS{Value: mapping.Value},
+		"RoleID":     &awsv2types.AttributeValueMemberS{Value: mapping.RoleID},
+		"Sync":       &awsv2types.AttributeValueMemberBOOL{Value: mapping.Sync},
+		"CreatedAt":  &awsv2types.AttributeValueMemberS{Value: mapping.CreatedAt.Format(time.RFC3339)},
+		"UpdatedAt":  &awsv2types.AttributeValueMemberS{Value: mapping.UpdatedAt.Format(time.RFC3339)},
+	}
+	return xray.Capture(ctx, "DynamoDB.PutClaimMapping", func(ctx context.Context) error {
+		_, err := r.client.db.PutItem(ctx, &awsv2dynamodb.PutItemInput{
+			TableName:           aws.String(r.client.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrConflict
+		}
+		return err
+	})
+}
+
+func (r *ClaimMappingRepository) Update(ctx context.Context, mapping domain.ClaimMapping) error {
+	return xray.Capture(ctx, "DynamoDB.UpdateClaimMapping", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName:        aws.String(r.client.tableName),
+			Key:              claimMappingKey(mapping.AppID, mapping.ID),
+			UpdateExpression: aws.String("SET Claim = :c, #v = :v, RoleID = :r, #s = :s, UpdatedAt = :u"),
+			ExpressionAttributeNames: map[string]string{
+				"#v": "Value",
+				"#s": "Sync",
+			},
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":c": &awsv2types.AttributeValueMemberS{Value: mapping.Claim},
+				":v": &awsv2types.AttributeValueMemberS{Value: mapping.Value},
+				":r": &awsv2types.AttributeValueMemberS{Value: mapping.RoleID},
+				":s": &awsv2types.AttributeValueMemberBOOL{Value: mapping.Sync},
+				":u": &awsv2types.AttributeValueMemberS{Value: mapping.UpdatedAt.Format(time.RFC3339)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *ClaimMappingRepository) Delete(ctx context.Context, appID, mappingID string) error {
+	return xray.Capture(ctx, "DynamoDB.DeleteClaimMapping", func(ctx context.Context) error {
+		_, err := r.client.db.DeleteItem(ctx, &awsv2dynamodb.DeleteItemInput{
+			TableName:           aws.String(r.client.tableName),
+			Key:                 claimMappingKey(appID, mappingID),
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *ClaimMappingRepository) Get(ctx context.Context, appID, mappingID string) (domain.ClaimMapping, error) {
+	var out *awsv2dynamodb.GetItemOutput
+	err := xray.Capture(ctx, "DynamoDB.GetClaimMapping", func(ctx context.Context) error {
+		var e error
+		out, e = r.client.db.GetItem(ctx, &awsv2dynamodb.GetItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key:       claimMappingKey(appID, mappingID),
+		})
+		return e
+	})
+	if err != nil {
+		return domain.ClaimMapping{}, err
+	}
+	if out.Item == nil {
+		return domain.ClaimMapping{}, domain.ErrNotFound
+	}
+	return claimMappingFromItem(appID, out.Item)
+}
+
+func (r *ClaimMappingRepository) ListByAppID(ctx context.Context, appID string) ([]domain.ClaimMapping, error) {
+	items, err := r.client.queryAll(ctx, "DynamoDB.QueryClaimMappings", claimMappingsQuery(appID))
+	if err != nil {
+		return nil, err
+	}
+	return claimMappingsFromItems(appID, items)
+}
+
+func (r *ClaimMappingRepository) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.ClaimMapping], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryClaimMappings", claimMappingsQuery(appID), page)
+	if err != nil {
+		return domain.Page[domain.ClaimMapping]{}, err
+	}
+	mappings, err := claimMappingsFromItems(appID, items)
+	if err != nil {
+		return domain.Page[domain.ClaimMapping]{}, err
+	}
+	return domain.Page[domain.ClaimMapping]{Items: mappings, NextToken: next}, nil
+}
+
+func claimMappingsQuery(appID string) *awsv2dynamodb.QueryInput {
+	return &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: "CLAIMMAP#"},
+		},
+	}
+}
+
+func claimMappingsFromItems(appID string, items []map[string]awsv2types.AttributeValue) ([]domain.ClaimMapping, error) {
+	mappings := make([]domain.ClaimMapping, 0, len(items))
+	for _, item := range items {
+		mapping, err := claimMappingFromItem(appID, item)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+func claimMappingFromItem(appID string, item map[string]awsv2types.AttributeValue) (domain.ClaimMapping, error) {
+	raw := struct {
+		ID        string `dynamodbav:"ID"`
+		Claim     string `dynamodbav:"Claim"`
+		Value     string `dynamodbav:"Value"`
+		RoleID    string `dynamodbav:"RoleID"`
+		Sync      bool   `dynamodbav:"Sync"`
+		CreatedAt string `dynamodbav:"CreatedAt"`
+		UpdatedAt string `dynamodbav:"UpdatedAt"`
+	}{}
+	if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
+		return domain.ClaimMapping{}, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, raw.CreatedAt)
+	updatedAt, _ := time.Parse(time.RFC3339, raw.UpdatedAt)
+	return domain.ClaimMapping{
+		AppID:     appID,
+		ID:        raw.ID,
+		Claim:     raw.Claim,
+		Value:     raw.Value,
+		RoleID:    raw.RoleID,
+		Sync:      raw.Sync,
+		CreatedAt: createdAt,
+		UpdatedAt: updatedAt,
+	}, nil
+}
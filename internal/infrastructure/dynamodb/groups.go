@@ -0,0 +1,353 @@
+package dynamodb
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/domain"
+)
+
+// Memberships are GMEMBER#<group_id>#<user_id> items mirrored in the Groups
+// set of the user's assignment.
+
+func groupSK(groupID string) string { return "GROUP#" + groupID }
+
+func groupMemberSK(groupID, userID string) string { return "GMEMBER#" + groupID + "#" + userID }
+
+type GroupRepository struct{ client *Client }
+
+func NewGroupRepository(client *Client) *GroupRepository {
+	return &GroupRepository{client: client}
+}
+
+func (r *GroupRepository) Create(ctx context.Context, group domain.Group) error {
+	item := map[string]awsv2types.AttributeValue{
+		"PK":          &awsv2types.AttributeValueMemberS{Value: appPK(group.AppID)},
+		"SK":          &awsv2types.AttributeValueMemberS{Value: groupSK(group.ID)},
+		"EntityType":  &awsv2types.AttributeValueMemberS{Value: "GROUP"},
+		"ID":          &awsv2types.AttributeValueMemberS{Value: group.ID},
+		"Name":        &awsv2types.AttributeValueMemberS{Value: group.Name},
+		"Description": &awsv2types.AttributeValueMemberS{Value: group.Description},
+		"CreatedAt":   &awsv2types.AttributeValueMemberS{Value: group.CreatedAt.Format(time.RFC3339)},
+		"UpdatedAt":   &awsv2types.AttributeValueMemberS{Value: group.UpdatedAt.Format(time.RFC3339)},
+	}
+	// DynamoDB rejects empty sets, so a group without roles has no Roles.
+	if len(group.Roles) > 0 {
+		item["Roles"] = &awsv2types.AttributeValueMemberSS{Value: group.Roles}
+	}
+	return xray.Capture(ctx, "DynamoDB.PutGroup", func(ctx context.Context) error {
+		_, err := r.client.db.PutItem(ctx, &awsv2dynamodb.PutItemInput{
+			TableName:           aws.String(r.client.tableName),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(PK) AND attribute_not_exists(SK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrConflict
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) Update(ctx context.Context, group domain.Group) error {
+	return xray.Capture(ctx, "DynamoDB.UpdateGroup", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName:        aws.String(r.client.tableName),
+			Key:              groupKey(group.AppID, group.ID),
+			UpdateExpression: aws.String("SET #n = :n, #d = :d, UpdatedAt = :u"),
+			ExpressionAttributeNames: map[string]string{
+				"#n": "Name",
+				"#d": "Description",
+			},
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":n": &awsv2types.AttributeValueMemberS{Value: group.Name},
+				":d": &awsv2types.AttributeValueMemberS{Value: group.Description},
+				":u": &awsv2types.AttributeValueMemberS{Value: group.UpdatedAt.Format(time.RFC3339)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+// Delete removes the group item only; members must be removed first.
+func (r *GroupRepository) Delete(ctx context.Context, appID, groupID string) error {
+	return xray.Capture(ctx, "DynamoDB.DeleteGroup", func(ctx context.Context) error {
+		_, err := r.client.db.DeleteItem(ctx, &awsv2dynamodb.DeleteItemInput{
+			TableName:           aws.String(r.client.tableName),
+			Key:                 groupKey(appID, groupID),
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) Get(ctx context.Context, appID, groupID string) (domain.Group, error) {
+	var out *awsv2dynamodb.GetItemOutput
+	err := xray.Capture(ctx, "DynamoDB.GetGroup", func(ctx context.Context) error {
+		var e error
+		out, e = r.client.db.GetItem(ctx, &awsv2dynamodb.GetItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key:       groupKey(appID, groupID),
+		})
+		return e
+	})
+	if err != nil {
+		return domain.Group{}, err
+	}
+	if out.Item == nil {
+		return domain.Group{}, domain.ErrNotFound
+	}
+	return groupFromItem(appID, out.Item)
+}
+
+func (r *GroupRepository) GetMany(ctx context.Context, appID string, groupIDs []string) ([]domain.Group, error) {
+	keys := make([]map[string]awsv2types.AttributeValue, 0, len(groupIDs))
+	for _, groupID := range groupIDs {
+		keys = append(keys, groupKey(appID, groupID))
+	}
+	items, err := r.client.batchGet(ctx, "DynamoDB.BatchGetGroups", keys)
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]domain.Group, 0, len(items))
+	for _, item := range items {
+		group, err := groupFromItem(appID, item)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	// BatchGetItem returns items in no particular order.
+	slices.SortFunc(groups, func(a, b domain.Group) int {
+		return slices.Index(groupIDs, a.ID) - slices.Index(groupIDs, b.ID)
+	})
+	return groups, nil
+}
+
+func (r *GroupRepository) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Group], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryGroups", &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: "GROUP#"},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.Group]{}, err
+	}
+	groups := make([]domain.Group, 0, len(items))
+	for _, item := range items {
+		group, err := groupFromItem(appID, item)
+		if err != nil {
+			return domain.Page[domain.Group]{}, err
+		}
+		groups = append(groups, group)
+	}
+	return domain.Page[domain.Group]{Items: groups, NextToken: next}, nil
+}
+
+func (r *GroupRepository) AssignRole(ctx context.Context, appID, groupID, roleID string) error {
+	return xray.Capture(ctx, "DynamoDB.AssignGroupRole", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName:        aws.String(r.client.tableName),
+			Key:              groupKey(appID, groupID),
+			UpdateExpression: aws.String("ADD Roles :r SET UpdatedAt = :u"),
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":r": &awsv2types.AttributeValueMemberSS{Value: []string{roleID}},
+				":u": &awsv2types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) RevokeRole(ctx context.Context, appID, groupID, roleID string) error {
+	return xray.Capture(ctx, "DynamoDB.RevokeGroupRole", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName:        aws.String(r.client.tableName),
+			Key:              groupKey(appID, groupID),
+			UpdateExpression: aws.String("DELETE Roles :r SET UpdatedAt = :u"),
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":r":    &awsv2types.AttributeValueMemberSS{Value: []string{roleID}},
+				":role": &awsv2types.AttributeValueMemberS{Value: roleID},
+				":u":    &awsv2types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+			},
+			ConditionExpression: aws.String("contains(Roles, :role)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) AddMember(ctx context.Context, appID, groupID, userID string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	items := []awsv2types.TransactWriteItem{
+		{ConditionCheck: &awsv2types.ConditionCheck{
+			TableName:           aws.String(r.client.tableName),
+			Key:                 groupKey(appID, groupID),
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		}},
+		{Put: &awsv2types.Put{
+			TableName: aws.String(r.client.tableName),
+			Item: map[string]awsv2types.AttributeValue{
+				"PK":         &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				"SK":         &awsv2types.AttributeValueMemberS{Value: groupMemberSK(groupID, userID)},
+				"EntityType": &awsv2types.AttributeValueMemberS{Value: "GROUP_MEMBER"},
+				"GroupID":    &awsv2types.AttributeValueMemberS{Value: groupID},
+				"UserID":     &awsv2types.AttributeValueMemberS{Value: userID},
+				"AddedAt":    &awsv2types.AttributeValueMemberS{Value: now},
+			},
+		}},
+		{Update: &awsv2types.Update{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+			},
+			UpdateExpression:         aws.String("ADD #g :g SET EntityType = if_not_exists(EntityType, :t), Roles = if_not_exists(Roles, :nr), UpdatedAt = :u"),
+			ConditionExpression:      aws.String("attribute_not_exists(#g) OR size(#g) < :max OR contains(#g, :gid)"),
+			ExpressionAttributeNames: map[string]string{"#g": "Groups"},
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":g":   &awsv2types.AttributeValueMemberSS{Value: []string{groupID}},
+				":gid": &awsv2types.AttributeValueMemberS{Value: groupID},
+				":t":   &awsv2types.AttributeValueMemberS{Value: "USER_APP_ROLES"},
+				":nr":  &awsv2types.AttributeValueMemberL{Value: []awsv2types.AttributeValue{}},
+				":u":   &awsv2types.AttributeValueMemberS{Value: now},
+				":max": &awsv2types.AttributeValueMemberN{Value: strconv.Itoa(domain.MaxGroupsPerUser)},
+			},
+		}},
+	}
+	return xray.Capture(ctx, "DynamoDB.AddGroupMember", func(ctx context.Context) error {
+		_, err := r.client.db.TransactWriteItems(ctx, &awsv2dynamodb.TransactWriteItemsInput{TransactItems: items})
+		switch failedCondition(err) {
+		case 0:
+			return domain.ErrNotFound
+		case 2:
+			return domain.ErrConflict
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) RemoveMember(ctx context.Context, appID, groupID, userID string) error {
+	items := []awsv2types.TransactWriteItem{
+		{Delete: &awsv2types.Delete{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: groupMemberSK(groupID, userID)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		}},
+		{Update: &awsv2types.Update{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+			},
+			UpdateExpression:         aws.String("DELETE #g :g SET UpdatedAt = :u"),
+			ConditionExpression:      aws.String("attribute_exists(PK)"),
+			ExpressionAttributeNames: map[string]string{"#g": "Groups"},
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":g": &awsv2types.AttributeValueMemberSS{Value: []string{groupID}},
+				":u": &awsv2types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+			},
+		}},
+	}
+	return xray.Capture(ctx, "DynamoDB.RemoveGroupMember", func(ctx context.Context) error {
+		_, err := r.client.db.TransactWriteItems(ctx, &awsv2dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if failedCondition(err) >= 0 {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *GroupRepository) ListMembers(ctx context.Context, appID, groupID string, page domain.PageRequest) (domain.Page[domain.GroupMember], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryGroupMembers", &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: groupMemberSK(groupID, "")},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.GroupMember]{}, err
+	}
+	members := make([]domain.GroupMember, 0, len(items))
+	for _, item := range items {
+		raw := struct {
+			GroupID string `dynamodbav:"GroupID"`
+			UserID  string `dynamodbav:"UserID"`
+			AddedAt string `dynamodbav:"AddedAt"`
+		}{}
+		if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
+			return domain.Page[domain.GroupMember]{}, err
+		}
+		addedAt, _ := time.Parse(time.RFC3339, raw.AddedAt)
+		members = append(members, domain.GroupMember{AppID: appID, GroupID: raw.GroupID, UserID: raw.UserID, AddedAt: addedAt})
+	}
+	return domain.Page[domain.GroupMember]{Items: members, NextToken: next}, nil
+}
+
+func groupKey(appID, groupID string) map[string]awsv2types.AttributeValue {
+	return map[string]awsv2types.AttributeValue{
+		"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+		"SK": &awsv2types.AttributeValueMemberS{Value: groupSK(groupID)},
+	}
+}
+
+func groupFromItem(appID string, item map[string]awsv2types.AttributeValue) (domain.Group, error) {
+	raw := struct {
+		ID          string   `dynamodbav:"ID"`
+		Name        string   `dynamodbav:"Name"`
+		Description string   `dynamodbav:"Description"`
+		Roles       []string `dynamodbav:"Roles,stringset"`
+		CreatedAt   string   `dynamodbav:"CreatedAt"`
+		UpdatedAt   string   `dynamodbav:"UpdatedAt"`
+	}{}
+	if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
+		return domain.Group{}, err
+	}
+	createdAt, _ := time.Parse(time.RFC3339, raw.CreatedAt)
+	updatedAt, _ := time.Parse(time.RFC3339, raw.UpdatedAt)
+	roles := raw.Roles
+	if roles == nil {
+		roles = []string{}
+	}
+	slices.Sort(roles)
+	return domain.Group{AppID: appID, ID: raw.ID, Name: raw.Name, Description: raw.Description, Roles: roles, CreatedAt: createdAt, UpdatedAt: updatedAt}, nil
+}
+
+func failedCondition(err error) int {
+	var txErr *awsv2types.TransactionCanceledException
+	if !errors.As(err, &txErr) {
+		return -1
+	}
+	for i, reason := range txErr.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,53 @@
+package dynamodb
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+func leasePK(name string) string { return "LEASE#" + name }
+
+type LeaseRepository struct{ client *Client }
+
+func NewLeaseRepository(client *Client) *LeaseRepository {
+	return &LeaseRepository{client: client}
+}
+
+// Acquire reports false while another owner holds the lease.
+func (r *LeaseRepository) Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	acquired := false
+	err := xray.Capture(ctx, "DynamoDB.AcquireLease", func(ctx context.Context) error {
+		_, err := r.client.db.PutItem(ctx, &awsv2dynamodb.PutItemInput{
+			TableName: aws.String(r.client.tableName),
+			Item: map[string]awsv2types.AttributeValue{
+				"PK":         &awsv2types.AttributeValueMemberS{Value: leasePK(name)},
+				"SK":         &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
+				"EntityType": &awsv2types.AttributeValueMemberS{Value: "LEASE"},
+				"Owner":      &awsv2types.AttributeValueMemberS{Value: owner},
+				ttlAttribute: &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(now.Add(ttl).Unix(), 10)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(PK) OR #o = :o OR #ttl < :now"),
+			ExpressionAttributeNames: map[string]string{
+				"#o":   "Owner",
+				"#ttl": ttlAttribute,
+			},
+			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+				":o":   &awsv2types.AttributeValueMemberS{Value: owner},
+				":now": &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(now.Unix(), 10)},
+			},
+		})
+		if isConditionalCheckFailure(err) {
+			return nil
+		}
+		acquired = err == nil
+		return err
+	})
+	return acquired, err
+}
@@ -0,0 +1,233 @@
+package dynamodb
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"rbac-project/internal/domain"
+)
+
+const (
+	invertedIndex   = "InvertedIndex"
+	entityTypeIndex = "EntityTypeIndex"
+
+	// A filtered page may come back short after maxQueriesPerPage queries.
+	maxQueriesPerPage = 10
+
+	batchWriteLimit      = 25
+	batchWriteMaxRetries = 5
+
+	batchGetLimit = 100
+)
+
+type cursor struct {
+	Phase string            `json:"p,omitempty"`
+	Key   map[string]string `json:"k,omitempty"`
+}
+
+func encodeCursor(cur cursor) (string, error) {
+	raw, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(token string) (cursor, error) {
+	if token == "" {
+		return cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor{}, domain.ErrInvalidInput
+	}
+	var cur cursor
+	if err := json.Unmarshal(raw, &cur); err != nil {
+		return cursor{}, domain.ErrInvalidInput
+	}
+	return cur, nil
+}
+
+func keyFromCursor(key map[string]string) map[string]awsv2types.AttributeValue {
+	if len(key) == 0 {
+		return nil
+	}
+	out := make(map[string]awsv2types.AttributeValue, len(key))
+	for name, value := range key {
+		out[name] = &awsv2types.AttributeValueMemberS{Value: value}
+	}
+	return out
+}
+
+func keyToCursor(key map[string]awsv2types.AttributeValue) map[string]string {
+	if len(key) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(key))
+	for name, value := range key {
+		if s, ok := value.(*awsv2types.AttributeValueMemberS); ok {
+			out[name] = s.Value
+		}
+	}
+	return out
+}
+
+func stringAttr(item map[string]awsv2types.AttributeValue, name string) string {
+	if s, ok := item[name].(*awsv2types.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func (c *Client) queryPage(ctx context.Context, segment string, input *awsv2dynamodb.QueryInput, page domain.PageRequest) ([]map[string]awsv2types.AttributeValue, string, error) {
+	cur, err := decodeCursor(page.NextToken)
+	if err != nil {
+		return nil, "", err
+	}
+	items, lastKey, err := c.query(ctx, segment, input, page.Limit, keyFromCursor(cur.Key))
+	if err != nil || len(lastKey) == 0 {
+		return items, "", err
+	}
+	next, err := encodeCursor(cursor{Key: keyToCursor(lastKey)})
+	if err != nil {
+		return nil, "", err
+	}
+	return items, next, nil
+}
+
+func (c *Client) queryAll(ctx context.Context, segment string, input *awsv2dynamodb.QueryInput) ([]map[string]awsv2types.AttributeValue, error) {
+	var all []map[string]awsv2types.AttributeValue
+	var startKey map[string]awsv2types.AttributeValue
+	for {
+		items, lastKey, err := c.query(ctx, segment, input, 0, startKey)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+		if len(lastKey) == 0 {
+			return all, nil
+		}
+		startKey = lastKey
+	}
+}
+
+// fillPage builds mid-result tokens from the last item, so items must carry
+// every key of the queried index.
+func (c *Client) fillPage(ctx context.Context, segment string, input *awsv2dynamodb.QueryInput, page domain.PageRequest, keyAttrs []string) ([]map[string]awsv2types.AttributeValue, string, error) {
+	cur, err := decodeCursor(page.NextToken)
+	if err != nil {
+		return nil, "", err
+	}
+	startKey := keyFromCursor(cur.Key)
+	collected := make([]map[string]awsv2types.AttributeValue, 0, page.Limit)
+	for queries := 0; queries < maxQueriesPerPage; queries++ {
+		items, lastKey, err := c.query(ctx, segment, input, page.Limit, startKey)
+		if err != nil {
+			return nil, "", err
+		}
+		for i, item := range items {
+			collected = append(collected, item)
+			if len(collected) == page.Limit && (i < len(items)-1 || len(lastKey) > 0) {
+				key := make(map[string]string, len(keyAttrs))
+				for _, name := range keyAttrs {
+					key[name] = stringAttr(item, name)
+				}
+				next, err := encodeCursor(cursor{Key: key})
+				return collected, next, err
+			}
+		}
+		if len(lastKey) == 0 {
+			return collected, "", nil
+		}
+		startKey = lastKey
+	}
+	next, err := encodeCursor(cursor{Key: keyToCursor(startKey)})
+	return collected, next, err
+}
+
+func (c *Client) query(ctx context.Context, segment string, input *awsv2dynamodb.QueryInput, limit int, startKey map[string]awsv2types.AttributeValue) ([]map[string]awsv2types.AttributeValue, map[string]awsv2types.AttributeValue, error) {
+	input.TableName = aws.String(c.tableName)
+	input.ExclusiveStartKey = startKey
+	if limit > 0 {
+		input.Limit = aws.Int32(int32(limit))
+	}
+	var out *awsv2dynamodb.QueryOutput
+	err := xray.Capture(ctx, segment, func(ctx context.Context) error {
+		var e error
+		out, e = c.db.Query(ctx, input)
+		return e
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Items, out.LastEvaluatedKey, nil
+}
+
+func (c *Client) batchDelete(ctx context.Context, keys []map[string]awsv2types.AttributeValue) error {
+	for start := 0; start < len(keys); start += batchWriteLimit {
+		end := min(start+batchWriteLimit, len(keys))
+		requests := make([]awsv2types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			requests = append(requests, awsv2types.WriteRequest{DeleteRequest: &awsv2types.DeleteRequest{Key: key}})
+		}
+		pending := map[string][]awsv2types.WriteRequest{c.tableName: requests}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == batchWriteMaxRetries {
+				return errors.New("batch delete: unprocessed items after retries")
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt*50) * time.Millisecond)
+			}
+			var out *awsv2dynamodb.BatchWriteItemOutput
+			err := xray.Capture(ctx, "DynamoDB.BatchDelete", func(ctx context.Context) error {
+				var e error
+				out, e = c.db.BatchWriteItem(ctx, &awsv2dynamodb.BatchWriteItemInput{RequestItems: pending})
+				return e
+			})
+			if err != nil {
+				return err
+			}
+			pending = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+func (c *Client) batchGet(ctx context.Context, segment string, keys []map[string]awsv2types.AttributeValue) ([]map[string]awsv2types.AttributeValue, error) {
+	var items []map[string]awsv2types.AttributeValue
+	for start := 0; start < len(keys); start += batchGetLimit {
+		end := min(start+batchGetLimit, len(keys))
+		pending := map[string]awsv2types.KeysAndAttributes{c.tableName: {Keys: keys[start:end]}}
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt == batchWriteMaxRetries {
+				return nil, errors.New("batch get: unprocessed keys after retries")
+			}
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt*50) * time.Millisecond)
+			}
+			var out *awsv2dynamodb.BatchGetItemOutput
+			err := xray.Capture(ctx, segment, func(ctx context.Context) error {
+				var e error
+				out, e = c.db.BatchGetItem(ctx, &awsv2dynamodb.BatchGetItemInput{RequestItems: pending})
+				return e
+			})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, out.Responses[c.tableName]...)
+			pending = out.UnprocessedKeys
+		}
+	}
+	return items, nil
+}
+
+func primaryKey(item map[string]awsv2types.AttributeValue) map[string]awsv2types.AttributeValue {
+	return map[string]awsv2types.AttributeValue{"PK": item["PK"], "SK": item["SK"]}
+}
@@ -3,6 +3,12 @@ package dynamodb
 import (
 	"context"
 	"errors"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -15,8 +21,19 @@ import (
 	"rbac-project/internal/domain"
 )
 
+type dynamoAPI interface {
+	GetItem(ctx context.Context, params *awsv2dynamodb.GetItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *awsv2dynamodb.PutItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *awsv2dynamodb.UpdateItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *awsv2dynamodb.DeleteItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.DeleteItemOutput, error)
+	Query(ctx context.Context, params *awsv2dynamodb.QueryInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.QueryOutput, error)
+	BatchGetItem(ctx context.Context, params *awsv2dynamodb.BatchGetItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.BatchGetItemOutput, error)
+	BatchWriteItem(ctx context.Context, params *awsv2dynamodb.BatchWriteItemInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.BatchWriteItemOutput, error)
+	TransactWriteItems(ctx context.Context, params *awsv2dynamodb.TransactWriteItemsInput, optFns ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.TransactWriteItemsOutput, error)
+}
+
 type Client struct {
-	db        *awsv2dynamodb.Client
+	db        dynamoAPI
 	tableName string
 }
 
@@ -37,11 +54,33 @@ func permSK(permID string) string   { return "PERM#" + permID }
 func userPK(userID string) string   { return "USER#" + userID }
 func userAppSK(appID string) string { return "APP#" + appID }
 
+func memberSK(roleID, userID string) string { return "MEMBER#" + roleID + "#" + userID }
+
+func grantMemberSK(grant domain.RoleGrant, userID string) string {
+	if grant.Scope == "" {
+		return memberSK(grant.RoleID, userID)
+	}
+	return memberSK(grant.RoleID, userID) + "#" + grant.Scope
+}
+
 func isConditionalCheckFailure(err error) bool {
 	var condErr *awsv2types.ConditionalCheckFailedException
 	return errors.As(err, &condErr)
 }
 
+func isTransactionConditionFailure(err error) bool {
+	var txErr *awsv2types.TransactionCanceledException
+	if !errors.As(err, &txErr) {
+		return false
+	}
+	for _, reason := range txErr.CancellationReasons {
+		if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+			return true
+		}
+	}
+	return false
+}
+
 type ApplicationRepository struct{ client *Client }
 
 type RoleRepository struct{ client *Client }
@@ -77,6 +116,9 @@ func (r *ApplicationRepository) Create(ctx context.Context, app domain.Applicati
 		"CreatedAt":   app.CreatedAt.Format(time.RFC3339),
 		"UpdatedAt":   app.UpdatedAt.Format(time.RFC3339),
 	}
+	if app.StrictMode != nil {
+		item["StrictMode"] = *app.StrictMode
+	}
 	av, err := attributevalue.MarshalMap(item)
 	if err != nil {
 		return err
@@ -92,25 +134,30 @@ func (r *ApplicationRepository) Create(ctx context.Context, app domain.Applicati
 }
 
 func (r *ApplicationRepository) Update(ctx context.Context, app domain.Application) error {
+	input := &awsv2dynamodb.UpdateItemInput{
+		TableName: aws.String(r.client.tableName),
+		Key: map[string]awsv2types.AttributeValue{
+			"PK": &awsv2types.AttributeValueMemberS{Value: appPK(app.ID)},
+			"SK": &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
+		},
+		UpdateExpression: aws.String("SET #n = :n, #d = :d, UpdatedAt = :u"),
+		ExpressionAttributeNames: map[string]string{
+			"#n": "Name",
+			"#d": "Description",
+		},
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":n": &awsv2types.AttributeValueMemberS{Value: app.Name},
+			":d": &awsv2types.AttributeValueMemberS{Value: app.Description},
+			":u": &awsv2types.AttributeValueMemberS{Value: app.UpdatedAt.Format(time.RFC3339)},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+	}
+	if app.StrictMode != nil {
+		input.UpdateExpression = aws.String("SET #n = :n, #d = :d, UpdatedAt = :u, StrictMode = :s")
+		input.ExpressionAttributeValues[":s"] = &awsv2types.AttributeValueMemberBOOL{Value: *app.StrictMode}
+	}
 	return xray.Capture(ctx, "DynamoDB.UpdateApplication", func(ctx context.Context) error {
-		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
-			TableName: aws.String(r.client.tableName),
-			Key: map[string]awsv2types.AttributeValue{
-				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(app.ID)},
-				"SK": &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
-			},
-			UpdateExpression: aws.String("SET #n = :n, #d = :d, UpdatedAt = :u"),
-			ExpressionAttributeNames: map[string]string{
-				"#n": "Name",
-				"#d": "Description",
-			},
-			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
-				":n": &awsv2types.AttributeValueMemberS{Value: app.Name},
-				":d": &awsv2types.AttributeValueMemberS{Value: app.Description},
-				":u": &awsv2types.AttributeValueMemberS{Value: app.UpdatedAt.Format(time.RFC3339)},
-			},
-			ConditionExpression: aws.String("attribute_exists(PK)"),
-		})
+		_, err := r.client.db.UpdateItem(ctx, input)
 		if isConditionalCheckFailure(err) {
 			return domain.ErrNotFound
 		}
@@ -137,19 +184,155 @@ func (r *ApplicationRepository) GetByID(ctx context.Context, appID string) (doma
 	if out.Item == nil {
 		return domain.Application{}, domain.ErrNotFound
 	}
+	return applicationFromItem(out.Item)
+}
+
+func (r *ApplicationRepository) List(ctx context.Context, query domain.ApplicationQuery) (domain.Page[domain.Application], error) {
+	input := &awsv2dynamodb.QueryInput{
+		IndexName:              aws.String(entityTypeIndex),
+		KeyConditionExpression: aws.String("EntityType = :t"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":t": &awsv2types.AttributeValueMemberS{Value: "APPLICATION"},
+		},
+		ScanIndexForward: aws.Bool(!query.Descending),
+	}
+	if query.NamePrefix != "" {
+		input.FilterExpression = aws.String("begins_with(#n, :prefix)")
+		input.ExpressionAttributeNames = map[string]string{"#n": "Name"}
+		input.ExpressionAttributeValues[":prefix"] = &awsv2types.AttributeValueMemberS{Value: query.NamePrefix}
+	}
+	items, next, err := r.client.fillPage(ctx, "DynamoDB.QueryApplications", input, query.Page, []string{"EntityType", "CreatedAt", "PK", "SK"})
+	if err != nil {
+		return domain.Page[domain.Application]{}, err
+	}
+	apps := make([]domain.Application, 0, len(items))
+	for _, item := range items {
+		app, err := applicationFromItem(item)
+		if err != nil {
+			return domain.Page[domain.Application]{}, err
+		}
+		apps = append(apps, app)
+	}
+	return domain.Page[domain.Application]{Items: apps, NextToken: next}, nil
+}
+
+func applicationFromItem(item map[string]awsv2types.AttributeValue) (domain.Application, error) {
 	raw := struct {
 		ID          string `dynamodbav:"ID"`
 		Name        string `dynamodbav:"Name"`
 		Description string `dynamodbav:"Description"`
 		CreatedAt   string `dynamodbav:"CreatedAt"`
+		StrictMode  *bool  `dynamodbav:"StrictMode"`
 		UpdatedAt   string `dynamodbav:"UpdatedAt"`
 	}{}
-	if err := attributevalue.UnmarshalMap(out.Item, &raw); err != nil {
+	if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
 		return domain.Application{}, err
 	}
 	createdAt, _ := time.Parse(time.RFC3339, raw.CreatedAt)
 	updatedAt, _ := time.Parse(time.RFC3339, raw.UpdatedAt)
-	return domain.Application{ID: raw.ID, Name: raw.Name, Description: raw.Description, CreatedAt: createdAt, UpdatedAt: updatedAt}, nil
+	app := domain.Application{ID: raw.ID, Name: raw.Name, Description: raw.Description, StrictMode: raw.StrictMode, CreatedAt: createdAt, UpdatedAt: updatedAt}
+	if app.StrictMode == nil {
+		strict := app.IsStrict()
+		app.StrictMode = &strict
+	}
+	return app, nil
+}
+
+const (
+	deletePhaseAssignments = "assignments"
+	deletePhasePartition   = "partition"
+
+	deleteBatchesPerCall = 10
+)
+
+func (r *ApplicationRepository) Delete(ctx context.Context, appID, nextToken string) (domain.ApplicationDeletion, error) {
+	result := domain.ApplicationDeletion{AppID: appID}
+	cur, err := decodeCursor(nextToken)
+	if err != nil {
+		return result, err
+	}
+	if cur.Phase == "" {
+		cur.Phase = deletePhaseAssignments
+	}
+	for batch := 0; batch < deleteBatchesPerCall; batch++ {
+		var input *awsv2dynamodb.QueryInput
+		switch cur.Phase {
+		case deletePhaseAssignments:
+			input = &awsv2dynamodb.QueryInput{
+				IndexName:              aws.String(invertedIndex),
+				KeyConditionExpression: aws.String("SK = :sk AND begins_with(PK, :pk)"),
+				ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+					":sk": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+					":pk": &awsv2types.AttributeValueMemberS{Value: "USER#"},
+				},
+			}
+		case deletePhasePartition:
+			input = &awsv2dynamodb.QueryInput{
+				KeyConditionExpression: aws.String("PK = :pk"),
+				ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+					":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				},
+			}
+		default:
+			return result, domain.ErrInvalidInput
+		}
+		items, lastKey, err := r.client.query(ctx, "DynamoDB.QueryApplicationItems", input, batchWriteLimit, keyFromCursor(cur.Key))
+		if err != nil {
+			return result, err
+		}
+		keys := make([]map[string]awsv2types.AttributeValue, 0, len(items))
+		for _, item := range items {
+			sk := stringAttr(item, "SK")
+			if cur.Phase == deletePhasePartition && sk == appMetaSK() {
+				continue
+			}
+			keys = append(keys, primaryKey(item))
+			switch {
+			case cur.Phase == deletePhaseAssignments:
+				result.AssignmentsRemoved++
+			case strings.HasPrefix(sk, "ROLE#"):
+				result.RolesRemoved++
+			case strings.HasPrefix(sk, "PERM#"):
+				result.PermissionsRemoved++
+			case strings.HasPrefix(sk, "GROUP#"):
+				result.GroupsRemoved++
+			case strings.HasPrefix(sk, "CLAIMMAP#"):
+				result.MappingsRemoved++
+			}
+		}
+		if err := r.client.batchDelete(ctx, keys); err != nil {
+			return result, err
+		}
+		if len(lastKey) > 0 {
+			cur.Key = keyToCursor(lastKey)
+			continue
+		}
+		if cur.Phase == deletePhaseAssignments {
+			cur = cursor{Phase: deletePhasePartition}
+			continue
+		}
+		err = xray.Capture(ctx, "DynamoDB.DeleteApplication", func(ctx context.Context) error {
+			_, err := r.client.db.DeleteItem(ctx, &awsv2dynamodb.DeleteItemInput{
+				TableName: aws.String(r.client.tableName),
+				Key: map[string]awsv2types.AttributeValue{
+					"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+					"SK": &awsv2types.AttributeValueMemberS{Value: appMetaSK()},
+				},
+			})
+			return err
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Completed = true
+		return result, nil
+	}
+	token, err := encodeCursor(cur)
+	if err != nil {
+		return result, err
+	}
+	result.NextToken = token
+	return result, nil
 }
 
 func (r *RoleRepository) Create(ctx context.Context, role domain.Role) error {
@@ -159,9 +342,12 @@ func (r *RoleRepository) Create(ctx context.Context, role domain.Role) error {
 		"EntityType":  "ROLE",
 		"ID":          role.ID,
 		"Name":        role.Name,
+		"Parents":     role.Parents,
 		"Permissions": role.Permissions,
+		"Deny":        role.Deny,
+		"Conditions":  role.Conditions,
 		"CreatedAt":   role.CreatedAt.Format(time.RFC3339),
-		"UpdatedAt":   role.UpdatedAt.Format(time.RFC3339),
+		"UpdatedAt":   role.UpdatedAt.Format(time.RFC3339Nano),
 	}
 	av, err := attributevalue.MarshalMap(item)
 	if err != nil {
@@ -177,26 +363,89 @@ func (r *RoleRepository) Create(ctx context.Context, role domain.Role) error {
 	})
 }
 
-func (r *RoleRepository) Update(ctx context.Context, role domain.Role) error {
+// Update fails with ErrConflict if any role in unchanged, the previous
+// version of role included, no longer has the UpdatedAt it was read with.
+func (r *RoleRepository) Update(ctx context.Context, role domain.Role, unchanged []domain.Role) error {
 	permissionsAV, err := attributevalue.Marshal(role.Permissions)
 	if err != nil {
 		return err
 	}
+	parentsAV, err := attributevalue.Marshal(role.Parents)
+	if err != nil {
+		return err
+	}
+	denyAV, err := attributevalue.Marshal(role.Deny)
+	if err != nil {
+		return err
+	}
+	conditionsAV, err := attributevalue.Marshal(role.Conditions)
+	if err != nil {
+		return err
+	}
+	update := &awsv2types.Update{
+		TableName:        aws.String(r.client.tableName),
+		Key:              roleKey(role.AppID, role.ID),
+		UpdateExpression: aws.String("SET #n = :n, Parents = :pa, Permissions = :p, Deny = :dn, Conditions = :c, UpdatedAt = :u"),
+		ExpressionAttributeNames: map[string]string{
+			"#n": "Name",
+		},
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":n":  &awsv2types.AttributeValueMemberS{Value: role.Name},
+			":pa": parentsAV,
+			":p":  permissionsAV,
+			":dn": denyAV,
+			":c":  conditionsAV,
+			":u":  &awsv2types.AttributeValueMemberS{Value: role.UpdatedAt.Format(time.RFC3339Nano)},
+		},
+		ConditionExpression: aws.String("attribute_exists(PK)"),
+	}
+	items := []awsv2types.TransactWriteItem{{Update: update}}
+	for _, read := range unchanged {
+		condition, values := roleUnchanged(read)
+		if read.ID == role.ID {
+			update.ConditionExpression = aws.String("attribute_exists(PK) AND " + condition)
+			maps.Copy(update.ExpressionAttributeValues, values)
+			continue
+		}
+		items = append(items, awsv2types.TransactWriteItem{ConditionCheck: &awsv2types.ConditionCheck{
+			TableName:                 aws.String(r.client.tableName),
+			Key:                       roleKey(read.AppID, read.ID),
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeValues: values,
+		}})
+	}
 	return xray.Capture(ctx, "DynamoDB.UpdateRole", func(ctx context.Context) error {
-		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+		_, err := r.client.db.TransactWriteItems(ctx, &awsv2dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if isTransactionConditionFailure(err) {
+			return fmt.Errorf("%w: role %q or one of its parents changed concurrently", domain.ErrConflict, role.ID)
+		}
+		return err
+	})
+}
+
+func roleKey(appID, roleID string) map[string]awsv2types.AttributeValue {
+	return map[string]awsv2types.AttributeValue{
+		"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+		"SK": &awsv2types.AttributeValueMemberS{Value: roleSK(roleID)},
+	}
+}
+
+func roleUnchanged(read domain.Role) (string, map[string]awsv2types.AttributeValue) {
+	if read.UpdatedAt.IsZero() {
+		return "attribute_not_exists(UpdatedAt)", nil
+	}
+	return "UpdatedAt = :prev", map[string]awsv2types.AttributeValue{
+		":prev": &awsv2types.AttributeValueMemberS{Value: read.UpdatedAt.Format(time.RFC3339Nano)},
+	}
+}
+
+func (r *RoleRepository) Delete(ctx context.Context, appID, roleID string) error {
+	return xray.Capture(ctx, "DynamoDB.DeleteRole", func(ctx context.Context) error {
+		_, err := r.client.db.DeleteItem(ctx, &awsv2dynamodb.DeleteItemInput{
 			TableName: aws.String(r.client.tableName),
 			Key: map[string]awsv2types.AttributeValue{
-				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(role.AppID)},
-				"SK": &awsv2types.AttributeValueMemberS{Value: roleSK(role.ID)},
-			},
-			UpdateExpression: aws.String("SET #n = :n, Permissions = :p, UpdatedAt = :u"),
-			ExpressionAttributeNames: map[string]string{
-				"#n": "Name",
-			},
-			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
-				":n": &awsv2types.AttributeValueMemberS{Value: role.Name},
-				":p": permissionsAV,
-				":u": &awsv2types.AttributeValueMemberS{Value: role.UpdatedAt.Format(time.RFC3339)},
+				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: roleSK(roleID)},
 			},
 			ConditionExpression: aws.String("attribute_exists(PK)"),
 		})
@@ -208,37 +457,54 @@ func (r *RoleRepository) Update(ctx context.Context, role domain.Role) error {
 }
 
 func (r *RoleRepository) ListByAppID(ctx context.Context, appID string) ([]domain.Role, error) {
-	var out *awsv2dynamodb.QueryOutput
-	err := xray.Capture(ctx, "DynamoDB.QueryRoles", func(ctx context.Context) error {
-		var e error
-		out, e = r.client.db.Query(ctx, &awsv2dynamodb.QueryInput{
-			TableName:              aws.String(r.client.tableName),
-			KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
-			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
-				":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
-				":sk": &awsv2types.AttributeValueMemberS{Value: "ROLE#"},
-			},
-		})
-		return e
-	})
+	items, err := r.client.queryAll(ctx, "DynamoDB.QueryRoles", rolesQuery(appID))
 	if err != nil {
 		return nil, err
 	}
-	roles := make([]domain.Role, 0, len(out.Items))
-	for _, item := range out.Items {
+	return rolesFromItems(appID, items)
+}
+
+func (r *RoleRepository) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Role], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryRoles", rolesQuery(appID), page)
+	if err != nil {
+		return domain.Page[domain.Role]{}, err
+	}
+	roles, err := rolesFromItems(appID, items)
+	if err != nil {
+		return domain.Page[domain.Role]{}, err
+	}
+	return domain.Page[domain.Role]{Items: roles, NextToken: next}, nil
+}
+
+func rolesQuery(appID string) *awsv2dynamodb.QueryInput {
+	return &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: "ROLE#"},
+		},
+	}
+}
+
+func rolesFromItems(appID string, items []map[string]awsv2types.AttributeValue) ([]domain.Role, error) {
+	roles := make([]domain.Role, 0, len(items))
+	for _, item := range items {
 		raw := struct {
-			ID          string   `dynamodbav:"ID"`
-			Name        string   `dynamodbav:"Name"`
-			Permissions []string `dynamodbav:"Permissions"`
-			CreatedAt   string   `dynamodbav:"CreatedAt"`
-			UpdatedAt   string   `dynamodbav:"UpdatedAt"`
+			ID          string            `dynamodbav:"ID"`
+			Name        string            `dynamodbav:"Name"`
+			Parents     []string          `dynamodbav:"Parents"`
+			Permissions []string          `dynamodbav:"Permissions"`
+			Deny        []string          `dynamodbav:"Deny"`
+			Conditions  map[string]string `dynamodbav:"Conditions"`
+			CreatedAt   string            `dynamodbav:"CreatedAt"`
+			UpdatedAt   string            `dynamodbav:"UpdatedAt"`
 		}{}
 		if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
 			return nil, err
 		}
 		createdAt, _ := time.Parse(time.RFC3339, raw.CreatedAt)
 		updatedAt, _ := time.Parse(time.RFC3339, raw.UpdatedAt)
-		roles = append(roles, domain.Role{AppID: appID, ID: raw.ID, Name: raw.Name, Permissions: raw.Permissions, CreatedAt: createdAt, UpdatedAt: updatedAt})
+		roles = append(roles, domain.Role{AppID: appID, ID: raw.ID, Name: raw.Name, Parents: raw.Parents, Permissions: raw.Permissions, Deny: raw.Deny, Conditions: raw.Conditions, CreatedAt: createdAt, UpdatedAt: updatedAt})
 	}
 	return roles, nil
 }
@@ -267,25 +533,82 @@ func (r *PermissionRepository) Create(ctx context.Context, permission domain.Per
 	})
 }
 
-func (r *PermissionRepository) ListByAppID(ctx context.Context, appID string) ([]domain.Permission, error) {
-	var out *awsv2dynamodb.QueryOutput
-	err := xray.Capture(ctx, "DynamoDB.QueryPermissions", func(ctx context.Context) error {
-		var e error
-		out, e = r.client.db.Query(ctx, &awsv2dynamodb.QueryInput{
-			TableName:              aws.String(r.client.tableName),
-			KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+func (r *PermissionRepository) Update(ctx context.Context, permission domain.Permission) error {
+	return xray.Capture(ctx, "DynamoDB.UpdatePermission", func(ctx context.Context) error {
+		_, err := r.client.db.UpdateItem(ctx, &awsv2dynamodb.UpdateItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(permission.AppID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: permSK(permission.ID)},
+			},
+			UpdateExpression: aws.String("SET #n = :n, #d = :d"),
+			ExpressionAttributeNames: map[string]string{
+				"#n": "Name",
+				"#d": "Description",
+			},
 			ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
-				":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
-				":sk": &awsv2types.AttributeValueMemberS{Value: "PERM#"},
+				":n": &awsv2types.AttributeValueMemberS{Value: permission.Name},
+				":d": &awsv2types.AttributeValueMemberS{Value: permission.Description},
 			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
 		})
-		return e
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
+	})
+}
+
+func (r *PermissionRepository) Delete(ctx context.Context, appID, permissionID string) error {
+	return xray.Capture(ctx, "DynamoDB.DeletePermission", func(ctx context.Context) error {
+		_, err := r.client.db.DeleteItem(ctx, &awsv2dynamodb.DeleteItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: permSK(permissionID)},
+			},
+			ConditionExpression: aws.String("attribute_exists(PK)"),
+		})
+		if isConditionalCheckFailure(err) {
+			return domain.ErrNotFound
+		}
+		return err
 	})
+}
+
+func (r *PermissionRepository) ListByAppID(ctx context.Context, appID string) ([]domain.Permission, error) {
+	items, err := r.client.queryAll(ctx, "DynamoDB.QueryPermissions", permissionsQuery(appID))
 	if err != nil {
 		return nil, err
 	}
-	permissions := make([]domain.Permission, 0, len(out.Items))
-	for _, item := range out.Items {
+	return permissionsFromItems(appID, items)
+}
+
+func (r *PermissionRepository) ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Permission], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryPermissions", permissionsQuery(appID), page)
+	if err != nil {
+		return domain.Page[domain.Permission]{}, err
+	}
+	permissions, err := permissionsFromItems(appID, items)
+	if err != nil {
+		return domain.Page[domain.Permission]{}, err
+	}
+	return domain.Page[domain.Permission]{Items: permissions, NextToken: next}, nil
+}
+
+func permissionsQuery(appID string) *awsv2dynamodb.QueryInput {
+	return &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: "PERM#"},
+		},
+	}
+}
+
+func permissionsFromItems(appID string, items []map[string]awsv2types.AttributeValue) ([]domain.Permission, error) {
+	permissions := make([]domain.Permission, 0, len(items))
+	for _, item := range items {
 		raw := struct {
 			ID          string `dynamodbav:"ID"`
 			Name        string `dynamodbav:"Name"`
@@ -301,37 +624,363 @@ func (r *PermissionRepository) ListByAppID(ctx context.Context, appID string) ([
 	return permissions, nil
 }
 
-func (r *UserRoleRepository) AssignRole(ctx context.Context, appID, userID, roleID string) error {
-	current, err := r.GetByUserAndApp(ctx, appID, userID)
-	if err != nil && err != domain.ErrNotFound {
-		return err
+const (
+	maxAssignmentAttempts = 10
+
+	condAssignmentAbsent  = "attribute_not_exists(PK)"
+	condAssignmentVersion = "Version = :v"
+	condAssignmentLegacy  = "attribute_exists(PK) AND attribute_not_exists(Version)"
+
+	ttlAttribute = "TTL"
+)
+
+type storedGrant struct {
+	RoleID    string `dynamodbav:"RoleID,omitempty"`
+	Scope     string `dynamodbav:"Scope,omitempty"`
+	StartsAt  string `dynamodbav:"StartsAt,omitempty"`
+	ExpiresAt string `dynamodbav:"ExpiresAt,omitempty"`
+	Source    string `dynamodbav:"Source,omitempty"`
+}
+
+// Role IDs cannot contain "#", so the key cannot collide with another role's.
+func grantKey(roleID, scope string) string {
+	if scope == "" {
+		return roleID
 	}
-	roles := current.Roles
-	for _, role := range roles {
-		if role == roleID {
+	return roleID + "#" + scope
+}
+
+func storeGrant(grant domain.RoleGrant) storedGrant {
+	stored := storedGrant{RoleID: grant.RoleID, Scope: grant.Scope, Source: grant.Source}
+	if grant.StartsAt != nil {
+		stored.StartsAt = grant.StartsAt.UTC().Format(time.RFC3339)
+	}
+	if grant.ExpiresAt != nil {
+		stored.ExpiresAt = grant.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return stored
+}
+
+func (g storedGrant) grant() domain.RoleGrant {
+	grant := domain.RoleGrant{RoleID: g.RoleID, Scope: g.Scope, Source: g.Source}
+	if t, err := time.Parse(time.RFC3339, g.StartsAt); err == nil {
+		grant.StartsAt = &t
+	}
+	if t, err := time.Parse(time.RFC3339, g.ExpiresAt); err == nil {
+		grant.ExpiresAt = &t
+	}
+	return grant
+}
+
+func grantsFromStored(roles []string, stored map[string]storedGrant) []domain.RoleGrant {
+	byRole := map[string][]domain.RoleGrant{}
+	for key, entry := range stored {
+		if entry.RoleID == "" {
+			entry.RoleID = key
+		}
+		byRole[entry.RoleID] = append(byRole[entry.RoleID], entry.grant())
+	}
+	grants := make([]domain.RoleGrant, 0, len(roles))
+	for _, roleID := range roles {
+		held := byRole[roleID]
+		if len(held) == 0 {
+			held = []domain.RoleGrant{{RoleID: roleID}}
+		}
+		slices.SortFunc(held, func(a, b domain.RoleGrant) int { return strings.Compare(a.Scope, b.Scope) })
+		grants = append(grants, held...)
+	}
+	return grants
+}
+
+func sameGrant(a, b domain.RoleGrant) bool {
+	return a.RoleID == b.RoleID && a.Scope == b.Scope
+}
+
+type grantsChange struct {
+	grants  []domain.RoleGrant
+	added   []domain.RoleGrant
+	removed []domain.RoleGrant
+}
+
+func (r *UserRoleRepository) AssignRole(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	return r.mutateGrants(ctx, appID, userID, false, "DynamoDB.AssignUserRole", func(grants []domain.RoleGrant) grantsChange {
+		i := slices.IndexFunc(grants, func(held domain.RoleGrant) bool { return sameGrant(held, grant) })
+		if i >= 0 {
+			grants[i] = grant
+		} else {
+			grants = append(grants, grant)
+		}
+		// Rewritten even when the grant exists, to backfill older assignments.
+		return grantsChange{grants: grants, added: []domain.RoleGrant{grant}}
+	})
+}
+
+func (r *UserRoleRepository) RevokeRole(ctx context.Context, appID, userID, roleID string) error {
+	return r.mutateGrants(ctx, appID, userID, true, "DynamoDB.RevokeUserRole", func(grants []domain.RoleGrant) grantsChange {
+		return splitGrants(grants, func(held domain.RoleGrant) bool { return held.RoleID == roleID })
+	})
+}
+
+func (r *UserRoleRepository) RevokeGrant(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	return r.mutateGrants(ctx, appID, userID, true, "DynamoDB.RevokeUserGrant", func(grants []domain.RoleGrant) grantsChange {
+		return splitGrants(grants, func(held domain.RoleGrant) bool { return sameGrant(held, grant) })
+	})
+}
+
+// RevokeSynced leaves the grant in place once something other than its
+// Source has rewritten it.
+func (r *UserRoleRepository) RevokeSynced(ctx context.Context, appID, userID string, grant domain.RoleGrant) error {
+	return r.mutateGrants(ctx, appID, userID, true, "DynamoDB.RevokeSyncedUserGrant", func(grants []domain.RoleGrant) grantsChange {
+		return splitGrants(grants, func(held domain.RoleGrant) bool { return sameGrant(held, grant) && held.Source == grant.Source })
+	})
+}
+
+func (r *UserRoleRepository) RevokeAllRoles(ctx context.Context, appID, userID string) error {
+	return r.mutateGrants(ctx, appID, userID, true, "DynamoDB.RevokeAllUserRoles", func(grants []domain.RoleGrant) grantsChange {
+		return grantsChange{grants: []domain.RoleGrant{}, removed: grants}
+	})
+}
+
+func (r *UserRoleRepository) PruneExpired(ctx context.Context, appID, userID string) error {
+	return r.mutateGrants(ctx, appID, userID, true, "DynamoDB.PruneUserRoles", func(grants []domain.RoleGrant) grantsChange {
+		return grantsChange{grants: grants}
+	})
+}
+
+func splitGrants(grants []domain.RoleGrant, revoke func(domain.RoleGrant) bool) grantsChange {
+	change := grantsChange{grants: make([]domain.RoleGrant, 0, len(grants))}
+	for _, held := range grants {
+		if revoke(held) {
+			change.removed = append(change.removed, held)
+		} else {
+			change.grants = append(change.grants, held)
+		}
+	}
+	return change
+}
+
+// mutateGrants retries from a fresh read when the Version it read is stale.
+func (r *UserRoleRepository) mutateGrants(ctx context.Context, appID, userID string, mustExist bool, segment string, change func(grants []domain.RoleGrant) grantsChange) error {
+	for attempt := 0; attempt < maxAssignmentAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(rand.IntN(10*attempt)+1) * time.Millisecond)
+		}
+		item, err := r.getAssignmentItem(ctx, appID, userID)
+		if err != nil {
+			return err
+		}
+		if item == nil && mustExist {
+			return domain.ErrNotFound
+		}
+		current := assignmentItem{}
+		if item != nil {
+			if err := attributevalue.UnmarshalMap(item, &current); err != nil {
+				return err
+			}
+		}
+		now := time.Now().UTC()
+		var expired []domain.RoleGrant
+		grants := slices.DeleteFunc(grantsFromStored(current.Roles, current.Grants), func(grant domain.RoleGrant) bool {
+			if grant.ExpiredAt(now) {
+				expired = append(expired, grant)
+				return true
+			}
+			return false
+		})
+		next := change(grants)
+		for _, grant := range expired {
+			matches := func(other domain.RoleGrant) bool { return sameGrant(other, grant) }
+			if !slices.ContainsFunc(next.removed, matches) && !slices.ContainsFunc(next.added, matches) {
+				next.removed = append(next.removed, grant)
+			}
+		}
+		if item != nil && len(next.added) == 0 && len(next.removed) == 0 {
 			return nil
 		}
+		err = r.writeGrants(ctx, appID, userID, item != nil, current.Version, next, segment)
+		if !isTransactionConditionFailure(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: concurrent updates to roles of user %s in app %s", domain.ErrConflict, userID, appID)
+}
+
+type assignmentItem struct {
+	Roles     []string               `dynamodbav:"Roles"`
+	Grants    map[string]storedGrant `dynamodbav:"Grants"`
+	Version   int64                  `dynamodbav:"Version"`
+	UpdatedAt string                 `dynamodbav:"UpdatedAt"`
+}
+
+func (r *UserRoleRepository) getAssignmentItem(ctx context.Context, appID, userID string) (map[string]awsv2types.AttributeValue, error) {
+	var out *awsv2dynamodb.GetItemOutput
+	err := xray.Capture(ctx, "DynamoDB.GetUserRolesConsistent", func(ctx context.Context) error {
+		var e error
+		out, e = r.client.db.GetItem(ctx, &awsv2dynamodb.GetItemInput{
+			TableName: aws.String(r.client.tableName),
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+			},
+			ConsistentRead: aws.Bool(true),
+		})
+		return e
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Item, nil
+}
+
+func (r *UserRoleRepository) writeGrants(ctx context.Context, appID, userID string, exists bool, version int64, change grantsChange, segment string) error {
+	roles := []string{}
+	stored := make(map[string]storedGrant, len(change.grants))
+	for _, grant := range change.grants {
+		if !slices.Contains(roles, grant.RoleID) {
+			roles = append(roles, grant.RoleID)
+		}
+		stored[grantKey(grant.RoleID, grant.Scope)] = storeGrant(grant)
 	}
-	roles = append(roles, roleID)
 	rolesAV, err := attributevalue.Marshal(roles)
 	if err != nil {
 		return err
 	}
-	return xray.Capture(ctx, "DynamoDB.PutUserRole", func(ctx context.Context) error {
-		_, err := r.client.db.PutItem(ctx, &awsv2dynamodb.PutItemInput{
+	grantsAV, err := attributevalue.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	update := &awsv2types.Update{
+		TableName: aws.String(r.client.tableName),
+		Key: map[string]awsv2types.AttributeValue{
+			"PK": &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
+			"SK": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+		},
+		UpdateExpression: aws.String("SET EntityType = :t, Roles = :r, Grants = :g, UpdatedAt = :u, Version = :nv"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":t":  &awsv2types.AttributeValueMemberS{Value: "USER_APP_ROLES"},
+			":r":  rolesAV,
+			":g":  grantsAV,
+			":u":  &awsv2types.AttributeValueMemberS{Value: now},
+			":nv": &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(version+1, 10)},
+		},
+	}
+	switch {
+	case !exists:
+		update.ConditionExpression = aws.String(condAssignmentAbsent)
+	case version == 0:
+		update.ConditionExpression = aws.String(condAssignmentLegacy)
+	default:
+		update.ConditionExpression = aws.String(condAssignmentVersion)
+		update.ExpressionAttributeValues[":v"] = &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(version, 10)}
+	}
+	items := []awsv2types.TransactWriteItem{{Update: update}}
+	for _, grant := range change.added {
+		member := map[string]awsv2types.AttributeValue{
+			"PK":         &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			"SK":         &awsv2types.AttributeValueMemberS{Value: grantMemberSK(grant, userID)},
+			"EntityType": &awsv2types.AttributeValueMemberS{Value: "ROLE_MEMBER"},
+			"RoleID":     &awsv2types.AttributeValueMemberS{Value: grant.RoleID},
+			"UserID":     &awsv2types.AttributeValueMemberS{Value: userID},
+			"AssignedAt": &awsv2types.AttributeValueMemberS{Value: now},
+		}
+		stored := storeGrant(grant)
+		if stored.Scope != "" {
+			member["Scope"] = &awsv2types.AttributeValueMemberS{Value: stored.Scope}
+		}
+		if stored.Source != "" {
+			member["Source"] = &awsv2types.AttributeValueMemberS{Value: stored.Source}
+		}
+		if stored.StartsAt != "" {
+			member["StartsAt"] = &awsv2types.AttributeValueMemberS{Value: stored.StartsAt}
+		}
+		if grant.ExpiresAt != nil {
+			member["ExpiresAt"] = &awsv2types.AttributeValueMemberS{Value: stored.ExpiresAt}
+			member[ttlAttribute] = &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(grant.ExpiresAt.Unix(), 10)}
+		}
+		items = append(items, awsv2types.TransactWriteItem{Put: &awsv2types.Put{
+			TableName: aws.String(r.client.tableName),
+			Item:      member,
+		}})
+	}
+	for _, grant := range change.removed {
+		items = append(items, awsv2types.TransactWriteItem{Delete: &awsv2types.Delete{
 			TableName: aws.String(r.client.tableName),
-			Item: map[string]awsv2types.AttributeValue{
-				"PK":         &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
-				"SK":         &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
-				"EntityType": &awsv2types.AttributeValueMemberS{Value: "USER_APP_ROLES"},
-				"Roles":      rolesAV,
-				"UpdatedAt":  &awsv2types.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+			Key: map[string]awsv2types.AttributeValue{
+				"PK": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+				"SK": &awsv2types.AttributeValueMemberS{Value: grantMemberSK(grant, userID)},
 			},
-		})
+		}})
+	}
+	return xray.Capture(ctx, segment, func(ctx context.Context) error {
+		_, err := r.client.db.TransactWriteItems(ctx, &awsv2dynamodb.TransactWriteItemsInput{TransactItems: items})
 		return err
 	})
 }
 
+func (r *UserRoleRepository) ListByUser(ctx context.Context, userID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryUserApps", &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: userPK(userID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: "APP#"},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.UserAppRoles]{}, err
+	}
+	apps := make([]domain.UserAppRoles, 0, len(items))
+	for _, item := range items {
+		userRoles, err := userAppRolesFromItem(item)
+		if err != nil {
+			return domain.Page[domain.UserAppRoles]{}, err
+		}
+		apps = append(apps, userRoles)
+	}
+	return domain.Page[domain.UserAppRoles]{Items: apps, NextToken: next}, nil
+}
+
+func (r *UserRoleRepository) ListByRole(ctx context.Context, appID, roleID string, page domain.PageRequest) (domain.Page[domain.RoleMember], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryRoleMembers", &awsv2dynamodb.QueryInput{
+		KeyConditionExpression: aws.String("PK = :pk AND begins_with(SK, :sk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":pk": &awsv2types.AttributeValueMemberS{Value: appPK(appID)},
+			":sk": &awsv2types.AttributeValueMemberS{Value: memberSK(roleID, "")},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.RoleMember]{}, err
+	}
+	members := make([]domain.RoleMember, 0, len(items))
+	for _, item := range items {
+		raw := struct {
+			RoleID     string `dynamodbav:"RoleID"`
+			UserID     string `dynamodbav:"UserID"`
+			AssignedAt string `dynamodbav:"AssignedAt"`
+			Scope      string `dynamodbav:"Scope"`
+			StartsAt   string `dynamodbav:"StartsAt"`
+			ExpiresAt  string `dynamodbav:"ExpiresAt"`
+			Source     string `dynamodbav:"Source"`
+		}{}
+		if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
+			return domain.Page[domain.RoleMember]{}, err
+		}
+		assignedAt, _ := time.Parse(time.RFC3339, raw.AssignedAt)
+		grant := storedGrant{StartsAt: raw.StartsAt, ExpiresAt: raw.ExpiresAt}.grant()
+		members = append(members, domain.RoleMember{
+			AppID:      appID,
+			RoleID:     raw.RoleID,
+			UserID:     raw.UserID,
+			Scope:      raw.Scope,
+			AssignedAt: assignedAt,
+			StartsAt:   grant.StartsAt,
+			ExpiresAt:  grant.ExpiresAt,
+			Source:     raw.Source,
+		})
+	}
+	return domain.Page[domain.RoleMember]{Items: members, NextToken: next}, nil
+}
+
 func (r *UserRoleRepository) GetByUserAndApp(ctx context.Context, appID, userID string) (domain.UserAppRoles, error) {
 	var out *awsv2dynamodb.GetItemOutput
 	err := xray.Capture(ctx, "DynamoDB.GetUserRoles", func(ctx context.Context) error {
@@ -351,13 +1000,52 @@ func (r *UserRoleRepository) GetByUserAndApp(ctx context.Context, appID, userID
 	if out.Item == nil {
 		return domain.UserAppRoles{}, domain.ErrNotFound
 	}
+	return userAppRolesFromItem(out.Item)
+}
+
+func (r *UserRoleRepository) ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error) {
+	items, next, err := r.client.queryPage(ctx, "DynamoDB.QueryAppUsers", &awsv2dynamodb.QueryInput{
+		IndexName:              aws.String(invertedIndex),
+		KeyConditionExpression: aws.String("SK = :sk AND begins_with(PK, :pk)"),
+		ExpressionAttributeValues: map[string]awsv2types.AttributeValue{
+			":sk": &awsv2types.AttributeValueMemberS{Value: userAppSK(appID)},
+			":pk": &awsv2types.AttributeValueMemberS{Value: "USER#"},
+		},
+	}, page)
+	if err != nil {
+		return domain.Page[domain.UserAppRoles]{}, err
+	}
+	users := make([]domain.UserAppRoles, 0, len(items))
+	for _, item := range items {
+		userRoles, err := userAppRolesFromItem(item)
+		if err != nil {
+			return domain.Page[domain.UserAppRoles]{}, err
+		}
+		users = append(users, userRoles)
+	}
+	return domain.Page[domain.UserAppRoles]{Items: users, NextToken: next}, nil
+}
+
+func userAppRolesFromItem(item map[string]awsv2types.AttributeValue) (domain.UserAppRoles, error) {
 	raw := struct {
-		Roles     []string `dynamodbav:"Roles"`
-		UpdatedAt string   `dynamodbav:"UpdatedAt"`
+		PK        string                 `dynamodbav:"PK"`
+		SK        string                 `dynamodbav:"SK"`
+		Roles     []string               `dynamodbav:"Roles"`
+		Grants    map[string]storedGrant `dynamodbav:"Grants"`
+		Groups    []string               `dynamodbav:"Groups,stringset"`
+		UpdatedAt string                 `dynamodbav:"UpdatedAt"`
 	}{}
-	if err := attributevalue.UnmarshalMap(out.Item, &raw); err != nil {
+	if err := attributevalue.UnmarshalMap(item, &raw); err != nil {
 		return domain.UserAppRoles{}, err
 	}
+	slices.Sort(raw.Groups)
 	updatedAt, _ := time.Parse(time.RFC3339, raw.UpdatedAt)
-	return domain.UserAppRoles{UserID: userID, AppID: appID, Roles: raw.Roles, UpdatedAt: updatedAt}, nil
+	return domain.UserAppRoles{
+		UserID:    strings.TrimPrefix(raw.PK, "USER#"),
+		AppID:     strings.TrimPrefix(raw.SK, "APP#"),
+		Roles:     raw.Roles,
+		Grants:    grantsFromStored(raw.Roles, raw.Grants),
+		Groups:    raw.Groups,
+		UpdatedAt: updatedAt,
+	}, nil
 }
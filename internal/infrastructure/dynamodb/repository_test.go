@@ -0,0 +1,418 @@
+package dynamodb
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsv2dynamodb "github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	awsv2types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"rbac-project/internal/domain"
+)
+
+func init() {
+	xray.Configure(xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy()})
+}
+
+// fakeDynamo is an in-memory table that understands exactly the item writes
+// and condition expressions the user role adapter issues.
+type fakeDynamo struct {
+	dynamoAPI
+	mu    sync.Mutex
+	items map[string]map[string]awsv2types.AttributeValue
+}
+
+func newFakeDynamo() *fakeDynamo {
+	return &fakeDynamo{items: map[string]map[string]awsv2types.AttributeValue{}}
+}
+
+func fakeKey(key map[string]awsv2types.AttributeValue) string {
+	return stringAttr(key, "PK") + "|" + stringAttr(key, "SK")
+}
+
+func (f *fakeDynamo) GetItem(_ context.Context, in *awsv2dynamodb.GetItemInput, _ ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	item := f.items[fakeKey(in.Key)]
+	f.mu.Unlock()
+	// Give other writers a chance to interleave between read and write.
+	runtime.Gosched()
+	return &awsv2dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (f *fakeDynamo) TransactWriteItems(_ context.Context, in *awsv2dynamodb.TransactWriteItemsInput, _ ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.TransactWriteItemsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, tx := range in.TransactItems {
+		holds := true
+		switch {
+		case tx.Update != nil:
+			holds = f.conditionHolds(tx.Update.Key, aws.ToString(tx.Update.ConditionExpression), tx.Update.ExpressionAttributeValues)
+		case tx.ConditionCheck != nil:
+			holds = f.conditionHolds(tx.ConditionCheck.Key, aws.ToString(tx.ConditionCheck.ConditionExpression), tx.ConditionCheck.ExpressionAttributeValues)
+		}
+		if !holds {
+			return nil, &awsv2types.TransactionCanceledException{
+				CancellationReasons: []awsv2types.CancellationReason{{Code: aws.String("ConditionalCheckFailed")}},
+			}
+		}
+	}
+	for _, tx := range in.TransactItems {
+		switch {
+		case tx.Update != nil:
+			key := fakeKey(tx.Update.Key)
+			item := map[string]awsv2types.AttributeValue{"PK": tx.Update.Key["PK"], "SK": tx.Update.Key["SK"]}
+			for _, assignment := range strings.Split(strings.TrimPrefix(aws.ToString(tx.Update.UpdateExpression), "SET "), ", ") {
+				name, placeholder, _ := strings.Cut(assignment, " = ")
+				item[name] = tx.Update.ExpressionAttributeValues[placeholder]
+			}
+			f.items[key] = item
+		case tx.Put != nil:
+			f.items[fakeKey(tx.Put.Item)] = tx.Put.Item
+		case tx.Delete != nil:
+			delete(f.items, fakeKey(tx.Delete.Key))
+		}
+	}
+	return &awsv2dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (f *fakeDynamo) PutItem(_ context.Context, in *awsv2dynamodb.PutItemInput, _ ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.conditionHolds(in.Item, aws.ToString(in.ConditionExpression), in.ExpressionAttributeValues) {
+		return nil, &awsv2types.ConditionalCheckFailedException{}
+	}
+	f.items[fakeKey(in.Item)] = in.Item
+	return &awsv2dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamo) conditionHolds(key map[string]awsv2types.AttributeValue, condition string, values map[string]awsv2types.AttributeValue) bool {
+	current, exists := f.items[fakeKey(key)]
+	switch condition {
+	case condAssignmentAbsent:
+		return !exists
+	case condAssignmentLegacy:
+		_, versioned := current["Version"]
+		return exists && !versioned
+	case condAssignmentVersion:
+		want := values[":v"].(*awsv2types.AttributeValueMemberN).Value
+		got, ok := current["Version"].(*awsv2types.AttributeValueMemberN)
+		return exists && ok && got.Value == want
+	case "attribute_not_exists(PK) OR #o = :o OR #ttl < :now":
+		if !exists || stringAttr(current, "Owner") == values[":o"].(*awsv2types.AttributeValueMemberS).Value {
+			return true
+		}
+		expires, _ := strconv.ParseInt(current[ttlAttribute].(*awsv2types.AttributeValueMemberN).Value, 10, 64)
+		now, _ := strconv.ParseInt(values[":now"].(*awsv2types.AttributeValueMemberN).Value, 10, 64)
+		return expires < now
+	case "UpdatedAt = :prev", "attribute_exists(PK) AND UpdatedAt = :prev":
+		return exists && stringAttr(current, "UpdatedAt") == values[":prev"].(*awsv2types.AttributeValueMemberS).Value
+	default:
+		panic("unexpected condition expression " + condition)
+	}
+}
+
+func (f *fakeDynamo) roles(t *testing.T, appID, userID string) []string {
+	t.Helper()
+	item := f.items[userPK(userID)+"|"+userAppSK(appID)]
+	require.NotNil(t, item)
+	assignment, err := userAppRolesFromItem(item)
+	require.NoError(t, err)
+	sort.Strings(assignment.Roles)
+	return assignment.Roles
+}
+
+func (f *fakeDynamo) members(appID string) []string {
+	var out []string
+	for _, item := range f.items {
+		if stringAttr(item, "EntityType") == "ROLE_MEMBER" && stringAttr(item, "PK") == appPK(appID) {
+			out = append(out, stringAttr(item, "RoleID"))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestUserRoleRepositoryAssignRoleConcurrent(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+
+	const workers = 20
+	var want []string
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		roleID := fmt.Sprintf("role-%02d", i)
+		want = append(want, roleID)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.AssignRole(context.Background(), "app1", "u1", domain.RoleGrant{RoleID: roleID})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, want, fake.roles(t, "app1", "u1"))
+	assert.Equal(t, want, fake.members("app1"))
+}
+
+func TestUserRoleRepositoryRevokeRoleConcurrent(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	for _, roleID := range []string{"keep", "a", "b", "c", "d"} {
+		require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: roleID}))
+	}
+
+	var wg sync.WaitGroup
+	for _, roleID := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, repo.RevokeRole(ctx, "app1", "u1", roleID))
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "e"}))
+	}()
+	wg.Wait()
+
+	assert.Equal(t, []string{"e", "keep"}, fake.roles(t, "app1", "u1"))
+	assert.Equal(t, []string{"e", "keep"}, fake.members("app1"))
+}
+
+func TestUserRoleRepositoryUpgradesLegacyItem(t *testing.T) {
+	fake := newFakeDynamo()
+	fake.items[userPK("u1")+"|"+userAppSK("app1")] = map[string]awsv2types.AttributeValue{
+		"PK":    &awsv2types.AttributeValueMemberS{Value: userPK("u1")},
+		"SK":    &awsv2types.AttributeValueMemberS{Value: userAppSK("app1")},
+		"Roles": &awsv2types.AttributeValueMemberL{Value: []awsv2types.AttributeValue{&awsv2types.AttributeValueMemberS{Value: "viewer"}}},
+	}
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+
+	require.NoError(t, repo.AssignRole(context.Background(), "app1", "u1", domain.RoleGrant{RoleID: "editor"}))
+
+	assert.Equal(t, []string{"editor", "viewer"}, fake.roles(t, "app1", "u1"))
+	assert.Equal(t, "1", fake.items[userPK("u1")+"|"+userAppSK("app1")]["Version"].(*awsv2types.AttributeValueMemberN).Value)
+}
+
+func TestUserRoleRepositoryRevokeRoleMissingAssignment(t *testing.T) {
+	repo := NewUserRoleRepository(&Client{db: newFakeDynamo(), tableName: "rbac"})
+
+	err := repo.RevokeRole(context.Background(), "app1", "u1", "viewer")
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRoleRepositoryPrunesExpiredGrants(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	soon := time.Now().Add(time.Hour)
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "viewer"}))
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "oncall", ExpiresAt: &soon}))
+
+	assignment, err := userAppRolesFromItem(fake.items[userPK("u1")+"|"+userAppSK("app1")])
+	require.NoError(t, err)
+	require.Len(t, assignment.Grants, 2)
+	assert.Nil(t, assignment.Grants[0].ExpiresAt)
+	require.NotNil(t, assignment.Grants[1].ExpiresAt)
+	assert.Equal(t, soon.Unix(), assignment.Grants[1].ExpiresAt.Unix())
+	member := fake.items[appPK("app1")+"|"+memberSK("oncall", "u1")]
+	assert.Equal(t, fmt.Sprint(soon.Unix()), member[ttlAttribute].(*awsv2types.AttributeValueMemberN).Value)
+
+	// Age the grant past its expiry, then let the next change prune it.
+	past := time.Now().Add(-time.Minute)
+	grantsAV, err := attributevalue.Marshal(map[string]storedGrant{
+		"viewer": storeGrant(domain.RoleGrant{RoleID: "viewer"}),
+		"oncall": storeGrant(domain.RoleGrant{RoleID: "oncall", ExpiresAt: &past}),
+	})
+	require.NoError(t, err)
+	fake.items[userPK("u1")+"|"+userAppSK("app1")]["Grants"] = grantsAV
+	require.NoError(t, repo.PruneExpired(ctx, "app1", "u1"))
+
+	assert.Equal(t, []string{"viewer"}, fake.roles(t, "app1", "u1"))
+	assert.Equal(t, []string{"viewer"}, fake.members("app1"))
+}
+
+func TestUserRoleRepositoryScopedGrants(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "editor"}))
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "editor", Scope: "org/acme"}))
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "editor", Scope: "org/globex"}))
+
+	assert.Equal(t, []string{"editor"}, fake.roles(t, "app1", "u1"))
+	assert.Equal(t, []string{"editor", "editor", "editor"}, fake.members("app1"))
+	member := fake.items[appPK("app1")+"|"+memberSK("editor", "u1")+"#org/acme"]
+	assert.Equal(t, "org/acme", stringAttr(member, "Scope"))
+
+	require.NoError(t, repo.RevokeGrant(ctx, "app1", "u1", domain.RoleGrant{RoleID: "editor", Scope: "org/acme"}))
+	assignment, err := userAppRolesFromItem(fake.items[userPK("u1")+"|"+userAppSK("app1")])
+	require.NoError(t, err)
+	assert.Equal(t, []domain.RoleGrant{{RoleID: "editor"}, {RoleID: "editor", Scope: "org/globex"}}, assignment.Grants)
+	assert.Equal(t, []string{"editor", "editor"}, fake.members("app1"))
+
+	require.NoError(t, repo.RevokeRole(ctx, "app1", "u1", "editor"))
+	assert.Empty(t, fake.roles(t, "app1", "u1"))
+	assert.Empty(t, fake.members("app1"))
+}
+
+func TestUserRoleRepositoryGrantKeysDoNotCollide(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "a", Scope: "b"}))
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", domain.RoleGrant{RoleID: "a@b"}))
+
+	item := fake.items[userPK("u1")+"|"+userAppSK("app1")]
+	assert.Len(t, item["Grants"].(*awsv2types.AttributeValueMemberM).Value, 2)
+	assignment, err := userAppRolesFromItem(item)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []domain.RoleGrant{{RoleID: "a", Scope: "b"}, {RoleID: "a@b"}}, assignment.Grants)
+	assert.False(t, assignment.HasAppWideGrant("a"))
+}
+
+func TestUserRoleRepositoryRevokeSynced(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewUserRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	synced := domain.RoleGrant{RoleID: "editor", Source: domain.ClaimMappingSource("m1")}
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u1", synced))
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u2", synced))
+	member := fake.items[appPK("app1")+"|"+memberSK("editor", "u1")]
+	assert.Equal(t, synced.Source, stringAttr(member, "Source"))
+
+	// An assignment made by hand since the sync takes the grant over.
+	require.NoError(t, repo.AssignRole(ctx, "app1", "u2", domain.RoleGrant{RoleID: "editor"}))
+	require.NoError(t, repo.RevokeSynced(ctx, "app1", "u1", synced))
+	require.NoError(t, repo.RevokeSynced(ctx, "app1", "u2", synced))
+
+	assert.Empty(t, fake.roles(t, "app1", "u1"))
+	assignment, err := userAppRolesFromItem(fake.items[userPK("u2")+"|"+userAppSK("app1")])
+	require.NoError(t, err)
+	assert.Equal(t, []domain.RoleGrant{{RoleID: "editor"}}, assignment.Grants)
+	assert.Equal(t, []string{"editor"}, fake.members("app1"))
+}
+
+// throttledBatchGet serves BatchGetItem from the fake table but leaves the
+// first requested key unprocessed on the first call.
+type throttledBatchGet struct {
+	*fakeDynamo
+	calls int
+}
+
+func (f *throttledBatchGet) BatchGetItem(_ context.Context, in *awsv2dynamodb.BatchGetItemInput, _ ...func(*awsv2dynamodb.Options)) (*awsv2dynamodb.BatchGetItemOutput, error) {
+	f.calls++
+	out := &awsv2dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]awsv2types.AttributeValue{}}
+	for table, req := range in.RequestItems {
+		keys := req.Keys
+		if f.calls == 1 {
+			out.UnprocessedKeys = map[string]awsv2types.KeysAndAttributes{table: {Keys: keys[:1]}}
+			keys = keys[1:]
+		}
+		for _, key := range keys {
+			if item, ok := f.items[fakeKey(key)]; ok {
+				out.Responses[table] = append(out.Responses[table], item)
+			}
+		}
+	}
+	return out, nil
+}
+
+func TestGroupRepositoryGetManyRetriesUnprocessedKeys(t *testing.T) {
+	fake := &throttledBatchGet{fakeDynamo: newFakeDynamo()}
+	for _, group := range []domain.Group{
+		{AppID: "app1", ID: "ops", Name: "Ops", Roles: []string{"viewer", "oncall"}},
+		{AppID: "app1", ID: "dev", Name: "Dev"},
+	} {
+		item := groupKey(group.AppID, group.ID)
+		item["ID"] = &awsv2types.AttributeValueMemberS{Value: group.ID}
+		item["Name"] = &awsv2types.AttributeValueMemberS{Value: group.Name}
+		if len(group.Roles) > 0 {
+			item["Roles"] = &awsv2types.AttributeValueMemberSS{Value: group.Roles}
+		}
+		fake.items[fakeKey(item)] = item
+	}
+	repo := NewGroupRepository(&Client{db: fake, tableName: "rbac"})
+
+	groups, err := repo.GetMany(context.Background(), "app1", []string{"ops", "missing", "dev"})
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, fake.calls)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "ops", groups[0].ID)
+	assert.Equal(t, []string{"oncall", "viewer"}, groups[0].Roles)
+	assert.Equal(t, "dev", groups[1].ID)
+	assert.Equal(t, []string{}, groups[1].Roles)
+}
+
+func TestRoleRepositoryUpdateRejectsStaleReads(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewRoleRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+	read := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, roleID := range []string{"viewer", "editor"} {
+		fake.items[appPK("a1")+"|"+roleSK(roleID)] = map[string]awsv2types.AttributeValue{
+			"PK":        &awsv2types.AttributeValueMemberS{Value: appPK("a1")},
+			"SK":        &awsv2types.AttributeValueMemberS{Value: roleSK(roleID)},
+			"UpdatedAt": &awsv2types.AttributeValueMemberS{Value: read.Format(time.RFC3339)},
+		}
+	}
+	viewer := domain.Role{AppID: "a1", ID: "viewer", UpdatedAt: read}
+	editor := domain.Role{AppID: "a1", ID: "editor", UpdatedAt: read}
+
+	updated := editor
+	updated.Parents = []string{"viewer"}
+	updated.UpdatedAt = read.Add(time.Millisecond)
+	require.NoError(t, repo.Update(ctx, updated, []domain.Role{editor, viewer}))
+
+	// This writer read editor before the update above and would close a cycle.
+	stale := viewer
+	stale.Parents = []string{"editor"}
+	stale.UpdatedAt = read.Add(2 * time.Millisecond)
+	err := repo.Update(ctx, stale, []domain.Role{viewer, editor})
+	require.ErrorIs(t, err, domain.ErrConflict)
+	assert.Equal(t, read.Format(time.RFC3339), stringAttr(fake.items[appPK("a1")+"|"+roleSK("viewer")], "UpdatedAt"))
+}
+
+func TestLeaseRepositoryAcquire(t *testing.T) {
+	fake := newFakeDynamo()
+	repo := NewLeaseRepository(&Client{db: fake, tableName: "rbac"})
+	ctx := context.Background()
+
+	held, err := repo.Acquire(ctx, "sweep", "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	held, err = repo.Acquire(ctx, "sweep", "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held, "held by a")
+
+	held, err = repo.Acquire(ctx, "sweep", "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held, "holder renews")
+
+	fake.items[leasePK("sweep")+"|"+appMetaSK()][ttlAttribute] = &awsv2types.AttributeValueMemberN{Value: strconv.FormatInt(time.Now().Add(-time.Second).Unix(), 10)}
+	held, err = repo.Acquire(ctx, "sweep", "b", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held, "expired lease is taken over")
+}
@@ -0,0 +1,130 @@
+package http
+
+import (
+	"context"
+	stdhttp "net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	"rbac-project/internal/application"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+)
+
+// Guard checks callers against the roles of domain.AdminAppID.
+type Guard struct {
+	authz  *application.AuthorizationService
+	logger ports.Logger
+}
+
+func NewGuard(authz *application.AuthorizationService, logger ports.Logger) *Guard {
+	return &Guard{authz: authz, logger: logger}
+}
+
+func (g *Guard) Require(permission func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if ok, err := g.allow(c, permission(c)); !ok {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// RequireUnlessCaller lets callers through without the permission when the
+// route's :user_id is their own.
+func (g *Guard) RequireUnlessCaller(permission func(c echo.Context) string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if userID, _ := c.Get("user_id").(string); userID != "" && userID == c.Param("user_id") {
+				return next(c)
+			}
+			if ok, err := g.allow(c, permission(c)); !ok {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
+
+// allow reports whether the caller holds required, having written the 401 or
+// 403 response when they do not.
+func (g *Guard) allow(c echo.Context, required string) (bool, error) {
+	ctx := c.Request().Context()
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return false, c.JSON(stdhttp.StatusUnauthorized, map[string]string{"error": "unauthenticated"})
+	}
+	req := domain.AccessRequest{AppID: domain.AdminAppID, UserID: userID, Permission: required}
+	req.Claims, _ = c.Get("claims").(map[string]any)
+	decision, err := g.authz.Decide(ctx, req)
+	if err == nil && decision.Allowed && domain.IsAdminAppPermission(required) {
+		decision.Allowed, err = g.grantedExactly(ctx, req)
+	}
+	if err != nil {
+		g.logger.Error(ctx, "management permission check failed", "user_id", userID, "permission", required, "error", err)
+		return false, handleError(c, err)
+	}
+	if !decision.Allowed {
+		g.logger.Warn(ctx, "management permission denied", "user_id", userID, "permission", required)
+		return false, c.JSON(stdhttp.StatusForbidden, map[string]string{"error": "forbidden", "required_permission": required})
+	}
+	return true, nil
+}
+
+// permitted reports which of permissions the caller holds, for filtering
+// listings.
+func (g *Guard) permitted(c echo.Context, permissions []string) ([]bool, error) {
+	ctx := c.Request().Context()
+	userID, _ := c.Get("user_id").(string)
+	if userID == "" {
+		return nil, domain.ErrUnauthorized
+	}
+	out := make([]bool, len(permissions))
+	if len(permissions) == 0 {
+		return out, nil
+	}
+	claims, _ := c.Get("claims").(map[string]any)
+	reqs := make([]domain.AccessRequest, len(permissions))
+	for i, permission := range permissions {
+		reqs[i] = domain.AccessRequest{AppID: domain.AdminAppID, UserID: userID, Permission: permission, Claims: claims}
+	}
+	decisions, err := g.authz.DecideBatch(ctx, reqs)
+	if err != nil {
+		return nil, err
+	}
+	for i, decision := range decisions {
+		out[i] = decision.Allowed
+		if out[i] && domain.IsAdminAppPermission(permissions[i]) {
+			if out[i], err = g.grantedExactly(ctx, reqs[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return out, nil
+}
+
+// grantedExactly keeps wildcards like roles:write:* from reaching the admin
+// application itself: only an exact grant or the super-admin's "*" does.
+func (g *Guard) grantedExactly(ctx context.Context, req domain.AccessRequest) (bool, error) {
+	permissions, err := g.authz.EffectivePermissions(ctx, req, false)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(permissions.Permissions, func(pattern string) bool {
+		return pattern == req.Permission || pattern == domain.PermissionWildcard
+	}), nil
+}
+
+func permissionFor(permission string) func(echo.Context) string {
+	return func(echo.Context) string { return permission }
+}
+
+func appPermission(permission func(appID string) string) func(echo.Context) string {
+	return func(c echo.Context) string { return permission(c.Param("app_id")) }
+}
+
+func idPermission(permission func(appID string) string) func(echo.Context) string {
+	return func(c echo.Context) string { return permission(c.Param("id")) }
+}
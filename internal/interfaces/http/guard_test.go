@@ -0,0 +1,231 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	adapterlogger "rbac-project/internal/adapters/logger"
+	"rbac-project/internal/application"
+	"rbac-project/internal/domain"
+	"rbac-project/internal/ports"
+)
+
+// stubUsers serves assignments by app and user; every other method of the
+// port is left unimplemented.
+type stubUsers struct {
+	ports.UserRoleRepository
+	roles map[string][]string
+}
+
+func (s stubUsers) GetByUserAndApp(_ context.Context, appID, userID string) (domain.UserAppRoles, error) {
+	roles, ok := s.roles[appID+"/"+userID]
+	if !ok {
+		return domain.UserAppRoles{}, domain.ErrNotFound
+	}
+	return domain.UserAppRoles{AppID: appID, UserID: userID, Roles: roles}, nil
+}
+
+type stubRoles struct {
+	ports.RoleRepository
+	roles map[string][]domain.Role
+}
+
+func (s stubRoles) ListByAppID(_ context.Context, appID string) ([]domain.Role, error) {
+	return s.roles[appID], nil
+}
+
+type stubMappings struct {
+	ports.ClaimMappingRepository
+}
+
+func (stubMappings) ListByAppID(context.Context, string) ([]domain.ClaimMapping, error) {
+	return nil, nil
+}
+
+// testAuthorization gives ops roles:write:*, apps:read:* and
+// assignments:read:docs, root the super-admin role and svc authorize:others:docs
+// and apps:read:docs in the admin application.
+func testAuthorization() *application.AuthorizationService {
+	users := stubUsers{roles: map[string][]string{
+		domain.AdminAppID + "/ops":  {"ops"},
+		domain.AdminAppID + "/root": {domain.SuperAdminRole},
+		domain.AdminAppID + "/svc":  {"svc"},
+		"docs/u1":                   {"reader"},
+		"docs/u2":                   {"reader"},
+	}}
+	roles := stubRoles{roles: map[string][]domain.Role{
+		domain.AdminAppID: {
+			{ID: "ops", Permissions: []string{"roles:write:*", "apps:read:*", domain.AssignmentsReadPermission("docs")}},
+			{ID: domain.SuperAdminRole, Permissions: []string{"*"}},
+			{ID: "svc", Permissions: []string{domain.AuthorizeOthersPermission("docs"), domain.AppsReadPermission("docs")}},
+		},
+		"docs": {{ID: "reader", Permissions: []string{"doc:read"}}},
+	}}
+	return application.NewAuthorizationService(users, roles, nil, stubMappings{})
+}
+
+type stubApps struct {
+	ports.ApplicationRepository
+	apps []domain.Application
+}
+
+func (s stubApps) List(context.Context, domain.ApplicationQuery) (domain.Page[domain.Application], error) {
+	return domain.Page[domain.Application]{Items: s.apps}, nil
+}
+
+func (s stubApps) GetByID(_ context.Context, appID string) (domain.Application, error) {
+	for _, app := range s.apps {
+		if app.ID == appID {
+			return app, nil
+		}
+	}
+	return domain.Application{}, domain.ErrNotFound
+}
+
+func testLogger() ports.Logger {
+	return adapterlogger.NewWithWriter(io.Discard, slog.LevelError)
+}
+
+// asCaller authenticates requests as the X-User header, like the auth
+// middleware would.
+func asCaller(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if userID := c.Request().Header.Get("X-User"); userID != "" {
+			c.Set("user_id", userID)
+			c.Set("claims", map[string]any{"sub": userID})
+		}
+		return next(c)
+	}
+}
+
+func serve(e *echo.Echo, method, path, userID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if userID != "" {
+		req.Header.Set("X-User", userID)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGuardRequire(t *testing.T) {
+	guard := NewGuard(testAuthorization(), testLogger())
+	e := echo.New()
+	e.Use(asCaller)
+	ok := func(c echo.Context) error { return c.NoContent(stdhttp.StatusOK) }
+	e.POST("/applications/:app_id/roles", ok, guard.Require(appPermission(domain.RolesWritePermission)))
+	e.GET("/applications/:app_id/users/:user_id", ok, guard.RequireUnlessCaller(appPermission(domain.AssignmentsReadPermission)))
+
+	cases := []struct {
+		name, method, path, user string
+		status                   int
+	}{
+		{"unauthenticated", stdhttp.MethodPost, "/applications/docs/roles", "", stdhttp.StatusUnauthorized},
+		{"missing permission", stdhttp.MethodPost, "/applications/docs/roles", "svc", stdhttp.StatusForbidden},
+		{"wildcard grant", stdhttp.MethodPost, "/applications/docs/roles", "ops", stdhttp.StatusOK},
+		{"wildcard grant on admin app", stdhttp.MethodPost, "/applications/" + domain.AdminAppID + "/roles", "ops", stdhttp.StatusForbidden},
+		{"super-admin on admin app", stdhttp.MethodPost, "/applications/" + domain.AdminAppID + "/roles", "root", stdhttp.StatusOK},
+		{"own assignment", stdhttp.MethodGet, "/applications/docs/users/u1", "u1", stdhttp.StatusOK},
+		{"someone else's assignment", stdhttp.MethodGet, "/applications/docs/users/u2", "u1", stdhttp.StatusForbidden},
+		{"read permission", stdhttp.MethodGet, "/applications/docs/users/u2", "ops", stdhttp.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(e, tc.method, tc.path, tc.user, "")
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	rec := serve(e, stdhttp.MethodPost, "/applications/docs/roles", "svc", "")
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, domain.RolesWritePermission("docs"), body["required_permission"])
+}
+
+func TestAuthorizationHandlerChecksOthers(t *testing.T) {
+	authz := testAuthorization()
+	h := NewAuthorizationHandler(authz, testLogger())
+	e := NewAuthorizationRouter(h, Middleware{Auth: asCaller, Guard: NewGuard(authz, testLogger())})
+
+	cases := []struct {
+		name, path, user, body string
+		status                 int
+	}{
+		{"own check", "/authorize", "u1", `{"app_id":"docs","permission":"doc:read"}`, stdhttp.StatusOK},
+		{"other user", "/authorize", "u1", `{"app_id":"docs","user_id":"u2","permission":"doc:read"}`, stdhttp.StatusForbidden},
+		{"other user with permission", "/authorize", "svc", `{"app_id":"docs","user_id":"u2","permission":"doc:read"}`, stdhttp.StatusOK},
+		{"other app", "/authorize", "svc", `{"app_id":"billing","user_id":"u2","permission":"doc:read"}`, stdhttp.StatusForbidden},
+		{"explain other user", "/authorize/explain", "u1", `{"app_id":"docs","user_id":"u2","permission":"doc:read"}`, stdhttp.StatusForbidden},
+		{"batch of own checks", "/authorize/batch", "u1", `{"checks":[{"app_id":"docs","permission":"doc:read"},{"app_id":"docs","user_id":"u1","permission":"doc:write"}]}`, stdhttp.StatusOK},
+		{"batch with other user", "/authorize/batch", "u1", `{"checks":[{"app_id":"docs","permission":"doc:read"},{"app_id":"docs","user_id":"u2","permission":"doc:read"}]}`, stdhttp.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := serve(e, stdhttp.MethodPost, tc.path, tc.user, tc.body)
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+
+	rec := serve(e, stdhttp.MethodGet, "/applications/docs/users/u2/permissions", "u1", "")
+	assert.Equal(t, stdhttp.StatusForbidden, rec.Code)
+	rec = serve(e, stdhttp.MethodGet, "/applications/docs/users/u1/permissions", "u1", "")
+	assert.Equal(t, stdhttp.StatusOK, rec.Code)
+}
+
+func TestApplicationsRequireReadPermission(t *testing.T) {
+	authz := testAuthorization()
+	apps := stubApps{apps: []domain.Application{{ID: "docs"}, {ID: "billing"}, {ID: domain.AdminAppID}, {ID: "legacy app"}}}
+	h := NewApplicationsHandler(application.NewApplicationService(apps, nil), testLogger())
+	e := NewApplicationsRouter(h, Middleware{Auth: asCaller, Guard: NewGuard(authz, testLogger())})
+
+	list := []struct {
+		user string
+		want []string
+	}{
+		{"svc", []string{"docs"}},
+		{"ops", []string{"docs", "billing"}},
+		{"root", []string{"docs", "billing", domain.AdminAppID}},
+		{"u1", []string{}},
+	}
+	for _, tc := range list {
+		t.Run("list as "+tc.user, func(t *testing.T) {
+			rec := serve(e, stdhttp.MethodGet, "/applications", tc.user, "")
+			require.Equal(t, stdhttp.StatusOK, rec.Code, rec.Body.String())
+			var page domain.Page[domain.Application]
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+			ids := []string{}
+			for _, app := range page.Items {
+				ids = append(ids, app.ID)
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+	assert.Equal(t, stdhttp.StatusUnauthorized, serve(e, stdhttp.MethodGet, "/applications", "", "").Code)
+
+	get := []struct {
+		user, appID string
+		status      int
+	}{
+		{"svc", "docs", stdhttp.StatusOK},
+		{"svc", "billing", stdhttp.StatusForbidden},
+		{"ops", "billing", stdhttp.StatusOK},
+		{"ops", domain.AdminAppID, stdhttp.StatusForbidden},
+		{"root", domain.AdminAppID, stdhttp.StatusOK},
+	}
+	for _, tc := range get {
+		t.Run("get "+tc.appID+" as "+tc.user, func(t *testing.T) {
+			rec := serve(e, stdhttp.MethodGet, "/applications/"+tc.appID, tc.user, "")
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+		})
+	}
+}
@@ -1,10 +1,16 @@
 package http
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	stdhttp "net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"rbac-project/internal/application"
@@ -13,19 +19,91 @@ import (
 )
 
 func handleError(c echo.Context, err error) error {
+	var unknownPermissions *domain.UnknownPermissionsError
 	switch {
+	case errors.As(err, &unknownPermissions):
+		return c.JSON(stdhttp.StatusUnprocessableEntity, map[string]any{
+			"error":               err.Error(),
+			"unknown_permissions": unknownPermissions.Permissions,
+		})
+	case errors.Is(err, domain.ErrUnprocessable):
+		return c.JSON(stdhttp.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
 	case errors.Is(err, domain.ErrInvalidInput):
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": err.Error()})
 	case errors.Is(err, domain.ErrNotFound):
 		return c.JSON(stdhttp.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, domain.ErrConflict):
+		return c.JSON(stdhttp.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, domain.ErrUnauthorized):
+		return c.JSON(stdhttp.StatusUnauthorized, map[string]string{"error": err.Error()})
 	default:
 		return c.JSON(stdhttp.StatusInternalServerError, map[string]string{"error": "internal error"})
 	}
 }
 
+func jsonWithETag(c echo.Context, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", "private, no-cache")
+	for _, candidate := range strings.Split(c.Request().Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return c.NoContent(stdhttp.StatusNotModified)
+		}
+	}
+	return c.JSONBlob(stdhttp.StatusOK, body)
+}
+
+// An empty appID is left to input validation.
+func apiKeyAllowsApp(c echo.Context, appID string) bool {
+	key, ok := c.Get("api_key").(domain.APIKey)
+	return !ok || appID == "" || key.AllowsApp(appID)
+}
+
+// forCaller only hands token claims to checks for the caller.
+func forCaller(c echo.Context, req *domain.AccessRequest) {
+	uid, ok := c.Get("user_id").(string)
+	if !ok {
+		return
+	}
+	if req.UserID == "" {
+		req.UserID = uid
+	}
+	if req.UserID == uid {
+		req.Claims, _ = c.Get("claims").(map[string]any)
+	}
+}
+
+func boolQueryParam(c echo.Context, name string) (bool, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(raw)
+}
+
+func pageRequest(c echo.Context) (domain.PageRequest, error) {
+	page := domain.PageRequest{NextToken: c.QueryParam("next_token")}
+	if raw := c.QueryParam("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return page, err
+		}
+		page.Limit = limit
+	}
+	return page, nil
+}
+
 type ApplicationsHandler struct {
 	service *application.ApplicationService
 	logger  ports.Logger
+	guard   *Guard
 }
 
 func NewApplicationsHandler(service *application.ApplicationService, logger ports.Logger) *ApplicationsHandler {
@@ -38,12 +116,13 @@ func (h *ApplicationsHandler) Create(c echo.Context) error {
 		ID          string `json:"id"`
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		StrictMode  *bool  `json:"strict_mode"`
 	}
 	if err := c.Bind(&req); err != nil {
 		h.logger.Warn(ctx, "invalid payload for create application", "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	if err := h.service.Create(ctx, domain.Application{ID: req.ID, Name: req.Name, Description: req.Description}); err != nil {
+	if err := h.service.Create(ctx, domain.Application{ID: req.ID, Name: req.Name, Description: req.Description, StrictMode: req.StrictMode}); err != nil {
 		h.logger.Error(ctx, "create application failed", "app_id", req.ID, "error", err)
 		return handleError(c, err)
 	}
@@ -55,12 +134,13 @@ func (h *ApplicationsHandler) Update(c echo.Context) error {
 	var req struct {
 		Name        string `json:"name"`
 		Description string `json:"description"`
+		StrictMode  *bool  `json:"strict_mode"`
 	}
 	if err := c.Bind(&req); err != nil {
 		h.logger.Warn(ctx, "invalid payload for update application", "app_id", c.Param("id"), "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	if err := h.service.Update(ctx, domain.Application{ID: c.Param("id"), Name: req.Name, Description: req.Description}); err != nil {
+	if err := h.service.Update(ctx, domain.Application{ID: c.Param("id"), Name: req.Name, Description: req.Description, StrictMode: req.StrictMode}); err != nil {
 		h.logger.Error(ctx, "update application failed", "app_id", c.Param("id"), "error", err)
 		return handleError(c, err)
 	}
@@ -77,6 +157,74 @@ func (h *ApplicationsHandler) Get(c echo.Context) error {
 	return c.JSON(stdhttp.StatusOK, app)
 }
 
+func (h *ApplicationsHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list applications", "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	query := domain.ApplicationQuery{NamePrefix: c.QueryParam("name_prefix"), Page: page}
+	switch c.QueryParam("order") {
+	case "", "asc":
+	case "desc":
+		query.Descending = true
+	default:
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid order"})
+	}
+	apps, err := h.service.List(ctx, query)
+	if err != nil {
+		h.logger.Error(ctx, "list applications failed", "error", err)
+		return handleError(c, err)
+	}
+	if h.guard != nil {
+		if apps.Items, err = h.readable(c, apps.Items); err != nil {
+			h.logger.Error(ctx, "list applications permission check failed", "error", err)
+			return handleError(c, err)
+		}
+	}
+	return c.JSON(stdhttp.StatusOK, apps)
+}
+
+// Applications created before IDs were validated cannot name a permission,
+// so they are left out.
+func (h *ApplicationsHandler) readable(c echo.Context, apps []domain.Application) ([]domain.Application, error) {
+	var named []domain.Application
+	var permissions []string
+	for _, app := range apps {
+		if domain.ValidateAppID(app.ID) == nil {
+			named = append(named, app)
+			permissions = append(permissions, domain.AppsReadPermission(app.ID))
+		}
+	}
+	allowed, err := h.guard.permitted(c, permissions)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]domain.Application, 0, len(apps))
+	for i, app := range named {
+		if allowed[i] {
+			out = append(out, app)
+		}
+	}
+	return out, nil
+}
+
+func (h *ApplicationsHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	force, err := boolQueryParam(c, "force")
+	if err != nil {
+		h.logger.Warn(ctx, "invalid force flag for delete application", "app_id", c.Param("id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid force flag"})
+	}
+	result, err := h.service.Delete(ctx, c.Param("id"), force, c.QueryParam("next_token"))
+	if err != nil {
+		h.logger.Error(ctx, "delete application failed", "app_id", c.Param("id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, result)
+}
+
 type RolesHandler struct {
 	service *application.RoleService
 	logger  ports.Logger
@@ -89,15 +237,18 @@ func NewRolesHandler(service *application.RoleService, logger ports.Logger) *Rol
 func (h *RolesHandler) Create(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req struct {
-		ID          string   `json:"id"`
-		Name        string   `json:"name"`
-		Permissions []string `json:"permissions"`
+		ID          string            `json:"id"`
+		Name        string            `json:"name"`
+		Parents     []string          `json:"parents"`
+		Permissions []string          `json:"permissions"`
+		Deny        []string          `json:"deny"`
+		Conditions  map[string]string `json:"conditions"`
 	}
 	if err := c.Bind(&req); err != nil {
 		h.logger.Warn(ctx, "invalid payload for create role", "app_id", c.Param("app_id"), "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	err := h.service.Create(ctx, domain.Role{AppID: c.Param("app_id"), ID: req.ID, Name: req.Name, Permissions: req.Permissions})
+	err := h.service.Create(ctx, domain.Role{AppID: c.Param("app_id"), ID: req.ID, Name: req.Name, Parents: req.Parents, Permissions: req.Permissions, Deny: req.Deny, Conditions: req.Conditions})
 	if err != nil {
 		h.logger.Error(ctx, "create role failed", "app_id", c.Param("app_id"), "role_id", req.ID, "error", err)
 		return handleError(c, err)
@@ -107,15 +258,12 @@ func (h *RolesHandler) Create(c echo.Context) error {
 
 func (h *RolesHandler) Update(c echo.Context) error {
 	ctx := c.Request().Context()
-	var req struct {
-		Name        string   `json:"name"`
-		Permissions []string `json:"permissions"`
-	}
+	var req domain.RoleUpdate
 	if err := c.Bind(&req); err != nil {
 		h.logger.Warn(ctx, "invalid payload for update role", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	err := h.service.Update(ctx, domain.Role{AppID: c.Param("app_id"), ID: c.Param("role_id"), Name: req.Name, Permissions: req.Permissions})
+	err := h.service.Update(ctx, c.Param("app_id"), c.Param("role_id"), req)
 	if err != nil {
 		h.logger.Error(ctx, "update role failed", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
 		return handleError(c, err)
@@ -123,8 +271,36 @@ func (h *RolesHandler) Update(c echo.Context) error {
 	return c.NoContent(stdhttp.StatusOK)
 }
 
+func (h *RolesHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	role, err := h.service.Get(ctx, c.Param("app_id"), c.Param("role_id"))
+	if err != nil {
+		h.logger.Error(ctx, "get role failed", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, role)
+}
+
+// Without limit or next_token, lists stay a bare JSON array.
+func isPaged(c echo.Context) bool {
+	return c.QueryParam("limit") != "" || c.QueryParam("next_token") != ""
+}
+
 func (h *RolesHandler) List(c echo.Context) error {
 	ctx := c.Request().Context()
+	if isPaged(c) {
+		page, err := pageRequest(c)
+		if err != nil {
+			h.logger.Warn(ctx, "invalid page for list roles", "app_id", c.Param("app_id"), "error", err)
+			return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+		}
+		roles, err := h.service.ListPageByAppID(ctx, c.Param("app_id"), page)
+		if err != nil {
+			h.logger.Error(ctx, "list roles failed", "app_id", c.Param("app_id"), "error", err)
+			return handleError(c, err)
+		}
+		return c.JSON(stdhttp.StatusOK, roles)
+	}
 	roles, err := h.service.ListByAppID(ctx, c.Param("app_id"))
 	if err != nil {
 		h.logger.Error(ctx, "list roles failed", "app_id", c.Param("app_id"), "error", err)
@@ -133,6 +309,21 @@ func (h *RolesHandler) List(c echo.Context) error {
 	return c.JSON(stdhttp.StatusOK, roles)
 }
 
+func (h *RolesHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	dryRun, err := boolQueryParam(c, "dry_run")
+	if err != nil {
+		h.logger.Warn(ctx, "invalid dry_run flag for delete role", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid dry_run flag"})
+	}
+	result, err := h.service.Delete(ctx, c.Param("app_id"), c.Param("role_id"), dryRun)
+	if err != nil {
+		h.logger.Error(ctx, "delete role failed", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, result)
+}
+
 type PermissionsHandler struct {
 	service *application.PermissionService
 	logger  ports.Logger
@@ -161,8 +352,54 @@ func (h *PermissionsHandler) Create(c echo.Context) error {
 	return c.NoContent(stdhttp.StatusCreated)
 }
 
+func (h *PermissionsHandler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for update permission", "app_id", c.Param("app_id"), "permission_id", c.Param("permission_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.Update(ctx, domain.Permission{AppID: c.Param("app_id"), ID: c.Param("permission_id"), Name: req.Name, Description: req.Description})
+	if err != nil {
+		h.logger.Error(ctx, "update permission failed", "app_id", c.Param("app_id"), "permission_id", c.Param("permission_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusOK)
+}
+
+func (h *PermissionsHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	cascade, err := boolQueryParam(c, "cascade")
+	if err != nil {
+		h.logger.Warn(ctx, "invalid cascade flag for delete permission", "app_id", c.Param("app_id"), "permission_id", c.Param("permission_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid cascade flag"})
+	}
+	result, err := h.service.Delete(ctx, c.Param("app_id"), c.Param("permission_id"), cascade)
+	if err != nil {
+		h.logger.Error(ctx, "delete permission failed", "app_id", c.Param("app_id"), "permission_id", c.Param("permission_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, result)
+}
+
 func (h *PermissionsHandler) List(c echo.Context) error {
 	ctx := c.Request().Context()
+	if isPaged(c) {
+		page, err := pageRequest(c)
+		if err != nil {
+			h.logger.Warn(ctx, "invalid page for list permissions", "app_id", c.Param("app_id"), "error", err)
+			return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+		}
+		permissions, err := h.service.ListPageByAppID(ctx, c.Param("app_id"), page)
+		if err != nil {
+			h.logger.Error(ctx, "list permissions failed", "app_id", c.Param("app_id"), "error", err)
+			return handleError(c, err)
+		}
+		return c.JSON(stdhttp.StatusOK, permissions)
+	}
 	permissions, err := h.service.ListByAppID(ctx, c.Param("app_id"))
 	if err != nil {
 		h.logger.Error(ctx, "list permissions failed", "app_id", c.Param("app_id"), "error", err)
@@ -183,13 +420,17 @@ func NewUsersHandler(service *application.UserService, logger ports.Logger) *Use
 func (h *UsersHandler) AssignRole(c echo.Context) error {
 	ctx := c.Request().Context()
 	var req struct {
-		RoleID string `json:"role_id"`
+		RoleID    string     `json:"role_id"`
+		Scope     string     `json:"scope"`
+		StartsAt  *time.Time `json:"starts_at"`
+		ExpiresAt *time.Time `json:"expires_at"`
 	}
 	if err := c.Bind(&req); err != nil {
 		h.logger.Warn(ctx, "invalid payload for assign role", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	err := h.service.AssignRole(ctx, c.Param("app_id"), c.Param("user_id"), req.RoleID)
+	grant := domain.RoleGrant{RoleID: req.RoleID, Scope: req.Scope, StartsAt: req.StartsAt, ExpiresAt: req.ExpiresAt}
+	err := h.service.AssignRole(ctx, c.Param("app_id"), c.Param("user_id"), grant)
 	if err != nil {
 		h.logger.Error(ctx, "assign role failed", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "role_id", req.RoleID, "error", err)
 		return handleError(c, err)
@@ -197,6 +438,31 @@ func (h *UsersHandler) AssignRole(c echo.Context) error {
 	return c.NoContent(stdhttp.StatusCreated)
 }
 
+func (h *UsersHandler) RevokeRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	var err error
+	if scope := c.QueryParam("scope"); scope != "" {
+		err = h.service.RevokeGrant(ctx, c.Param("app_id"), c.Param("user_id"), domain.RoleGrant{RoleID: c.Param("role_id"), Scope: scope})
+	} else {
+		err = h.service.RevokeRole(ctx, c.Param("app_id"), c.Param("user_id"), c.Param("role_id"))
+	}
+	if err != nil {
+		h.logger.Error(ctx, "revoke role failed", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "role_id", c.Param("role_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+func (h *UsersHandler) RevokeAllRoles(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.RevokeAllRoles(ctx, c.Param("app_id"), c.Param("user_id"))
+	if err != nil {
+		h.logger.Error(ctx, "revoke all roles failed", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
 func (h *UsersHandler) Get(c echo.Context) error {
 	ctx := c.Request().Context()
 	user, err := h.service.GetUserAppRoles(ctx, c.Param("app_id"), c.Param("user_id"))
@@ -207,9 +473,64 @@ func (h *UsersHandler) Get(c echo.Context) error {
 	return c.JSON(stdhttp.StatusOK, user)
 }
 
+func (h *UsersHandler) ListByApp(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list app users", "app_id", c.Param("app_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	users, err := h.service.ListAppUsers(ctx, c.Param("app_id"), page)
+	if err != nil {
+		h.logger.Error(ctx, "list app users failed", "app_id", c.Param("app_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, users)
+}
+
+func (h *UsersHandler) ListByRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list role members", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	members, err := h.service.ListRoleMembers(ctx, c.Param("app_id"), c.Param("role_id"), page)
+	if err != nil {
+		h.logger.Error(ctx, "list role members failed", "app_id", c.Param("app_id"), "role_id", c.Param("role_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, members)
+}
+
+func (h *UsersHandler) ListApplications(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list user applications", "user_id", c.Param("user_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	includePermissions := false
+	if raw := c.QueryParam("include"); raw != "" {
+		for _, expansion := range strings.Split(raw, ",") {
+			if strings.TrimSpace(expansion) != "permissions" {
+				return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid include"})
+			}
+			includePermissions = true
+		}
+	}
+	apps, err := h.service.ListUserApplications(ctx, c.Param("user_id"), includePermissions, page)
+	if err != nil {
+		h.logger.Error(ctx, "list user applications failed", "user_id", c.Param("user_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, apps)
+}
+
 type AuthorizationHandler struct {
 	service *application.AuthorizationService
 	logger  ports.Logger
+	guard   *Guard
 }
 
 func NewAuthorizationHandler(service *application.AuthorizationService, logger ports.Logger) *AuthorizationHandler {
@@ -222,24 +543,414 @@ func (h *AuthorizationHandler) Authorize(c echo.Context) error {
 		h.logger.Info(ctx, "authorize test mode enabled")
 		return c.JSON(stdhttp.StatusOK, map[string]bool{"allowed": true})
 	}
+	var req domain.AccessRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for authorize", "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	if !apiKeyAllowsApp(c, req.AppID) {
+		return c.JSON(stdhttp.StatusForbidden, map[string]string{"error": "api key not allowed for application"})
+	}
+	forCaller(c, &req)
+	if ok, err := h.allowOthers(c, req); !ok {
+		return err
+	}
+	decision, err := h.service.Decide(ctx, req)
+	if err != nil {
+		h.logger.Error(ctx, "authorize failed", "app_id", req.AppID, "user_id", req.UserID, "permission", req.Permission, "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, decision)
+}
+
+func (h *AuthorizationHandler) EffectivePermissions(c echo.Context) error {
+	ctx := c.Request().Context()
+	provenance, err := boolQueryParam(c, "provenance")
+	if err != nil {
+		h.logger.Warn(ctx, "invalid provenance flag for effective permissions", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid provenance flag"})
+	}
+	req := domain.AccessRequest{AppID: c.Param("app_id"), UserID: c.Param("user_id"), Resource: c.QueryParam("resource")}
+	forCaller(c, &req)
+	result, err := h.service.EffectivePermissions(ctx, req, provenance)
+	if err != nil {
+		h.logger.Error(ctx, "effective permissions failed", "app_id", c.Param("app_id"), "user_id", c.Param("user_id"), "error", err)
+		return handleError(c, err)
+	}
+	return jsonWithETag(c, result)
+}
+
+// allowOthers requires the authorize-others permission in every app where
+// reqs check someone other than the caller.
+func (h *AuthorizationHandler) allowOthers(c echo.Context, reqs ...domain.AccessRequest) (bool, error) {
+	if h.guard == nil {
+		return true, nil
+	}
+	caller, _ := c.Get("user_id").(string)
+	var checked []string
+	for _, req := range reqs {
+		if req.UserID == caller || slices.Contains(checked, req.AppID) {
+			continue
+		}
+		checked = append(checked, req.AppID)
+		if ok, err := h.guard.allow(c, domain.AuthorizeOthersPermission(req.AppID)); !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Explain ignores AUTHORIZE_TEST_MODE.
+func (h *AuthorizationHandler) Explain(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req domain.AccessRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for authorize explain", "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	if !apiKeyAllowsApp(c, req.AppID) {
+		return c.JSON(stdhttp.StatusForbidden, map[string]string{"error": "api key not allowed for application"})
+	}
+	forCaller(c, &req)
+	if ok, err := h.allowOthers(c, req); !ok {
+		return err
+	}
+	explanation, err := h.service.Explain(ctx, req)
+	if err != nil {
+		h.logger.Error(ctx, "authorize explain failed", "app_id", req.AppID, "user_id", req.UserID, "permission", req.Permission, "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, explanation)
+}
+
+func (h *AuthorizationHandler) AuthorizeBatch(c echo.Context) error {
+	ctx := c.Request().Context()
 	var req struct {
-		AppID      string `json:"app_id"`
-		UserID     string `json:"user_id"`
-		Permission string `json:"permission"`
+		Checks []domain.AccessRequest `json:"checks"`
 	}
 	if err := c.Bind(&req); err != nil {
-		h.logger.Warn(ctx, "invalid payload for authorize", "error", err)
+		h.logger.Warn(ctx, "invalid payload for authorize batch", "error", err)
 		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
 	}
-	if req.UserID == "" {
-		if uid, ok := c.Get("user_id").(string); ok {
-			req.UserID = uid
+	for _, check := range req.Checks {
+		if !apiKeyAllowsApp(c, check.AppID) {
+			return c.JSON(stdhttp.StatusForbidden, map[string]string{"error": "api key not allowed for application"})
 		}
 	}
-	allowed, err := h.service.IsAllowed(ctx, req.AppID, req.UserID, req.Permission)
+	if strings.EqualFold(os.Getenv("AUTHORIZE_TEST_MODE"), "true") {
+		h.logger.Info(ctx, "authorize test mode enabled")
+		decisions := make([]domain.Decision, len(req.Checks))
+		for i := range decisions {
+			decisions[i].Allowed = true
+		}
+		return c.JSON(stdhttp.StatusOK, map[string]any{"decisions": decisions})
+	}
+	for i := range req.Checks {
+		forCaller(c, &req.Checks[i])
+	}
+	if ok, err := h.allowOthers(c, req.Checks...); !ok {
+		return err
+	}
+	decisions, err := h.service.DecideBatch(ctx, req.Checks)
 	if err != nil {
-		h.logger.Error(ctx, "authorize failed", "app_id", req.AppID, "user_id", req.UserID, "permission", req.Permission, "error", err)
+		h.logger.Error(ctx, "authorize batch failed", "count", len(req.Checks), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, map[string]any{"decisions": decisions})
+}
+
+type ClaimMappingsHandler struct {
+	service *application.ClaimMappingService
+	logger  ports.Logger
+}
+
+func NewClaimMappingsHandler(service *application.ClaimMappingService, logger ports.Logger) *ClaimMappingsHandler {
+	return &ClaimMappingsHandler{service: service, logger: logger}
+}
+
+type claimMappingRequest struct {
+	ID     string `json:"id"`
+	Claim  string `json:"claim"`
+	Value  string `json:"value"`
+	RoleID string `json:"role_id"`
+	Sync   bool   `json:"sync"`
+}
+
+func (h *ClaimMappingsHandler) Create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req claimMappingRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for create claim mapping", "app_id", c.Param("app_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.Create(ctx, domain.ClaimMapping{AppID: c.Param("app_id"), ID: req.ID, Claim: req.Claim, Value: req.Value, RoleID: req.RoleID, Sync: req.Sync})
+	if err != nil {
+		h.logger.Error(ctx, "create claim mapping failed", "app_id", c.Param("app_id"), "mapping_id", req.ID, "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusCreated)
+}
+
+func (h *ClaimMappingsHandler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req claimMappingRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for update claim mapping", "app_id", c.Param("app_id"), "mapping_id", c.Param("mapping_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.Update(ctx, domain.ClaimMapping{AppID: c.Param("app_id"), ID: c.Param("mapping_id"), Claim: req.Claim, Value: req.Value, RoleID: req.RoleID, Sync: req.Sync})
+	if err != nil {
+		h.logger.Error(ctx, "update claim mapping failed", "app_id", c.Param("app_id"), "mapping_id", c.Param("mapping_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusOK)
+}
+
+func (h *ClaimMappingsHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	mapping, err := h.service.Get(ctx, c.Param("app_id"), c.Param("mapping_id"))
+	if err != nil {
+		h.logger.Error(ctx, "get claim mapping failed", "app_id", c.Param("app_id"), "mapping_id", c.Param("mapping_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, mapping)
+}
+
+func (h *ClaimMappingsHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list claim mappings", "app_id", c.Param("app_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	mappings, err := h.service.ListByAppID(ctx, c.Param("app_id"), page)
+	if err != nil {
+		h.logger.Error(ctx, "list claim mappings failed", "app_id", c.Param("app_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, mappings)
+}
+
+func (h *ClaimMappingsHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.Delete(ctx, c.Param("app_id"), c.Param("mapping_id"))
+	if err != nil {
+		h.logger.Error(ctx, "delete claim mapping failed", "app_id", c.Param("app_id"), "mapping_id", c.Param("mapping_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+type GroupsHandler struct {
+	service *application.GroupService
+	logger  ports.Logger
+}
+
+func NewGroupsHandler(service *application.GroupService, logger ports.Logger) *GroupsHandler {
+	return &GroupsHandler{service: service, logger: logger}
+}
+
+func (h *GroupsHandler) Create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		ID          string   `json:"id"`
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Roles       []string `json:"roles"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for create group", "app_id", c.Param("app_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.Create(ctx, domain.Group{AppID: c.Param("app_id"), ID: req.ID, Name: req.Name, Description: req.Description, Roles: req.Roles})
+	if err != nil {
+		h.logger.Error(ctx, "create group failed", "app_id", c.Param("app_id"), "group_id", req.ID, "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusCreated)
+}
+
+func (h *GroupsHandler) Update(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for update group", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.Update(ctx, domain.Group{AppID: c.Param("app_id"), ID: c.Param("group_id"), Name: req.Name, Description: req.Description})
+	if err != nil {
+		h.logger.Error(ctx, "update group failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusOK)
+}
+
+func (h *GroupsHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	group, err := h.service.Get(ctx, c.Param("app_id"), c.Param("group_id"))
+	if err != nil {
+		h.logger.Error(ctx, "get group failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, group)
+}
+
+func (h *GroupsHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list groups", "app_id", c.Param("app_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	groups, err := h.service.ListByAppID(ctx, c.Param("app_id"), page)
+	if err != nil {
+		h.logger.Error(ctx, "list groups failed", "app_id", c.Param("app_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, groups)
+}
+
+func (h *GroupsHandler) Delete(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.Delete(ctx, c.Param("app_id"), c.Param("group_id"))
+	if err != nil {
+		h.logger.Error(ctx, "delete group failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+func (h *GroupsHandler) AssignRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		RoleID string `json:"role_id"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for assign group role", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.AssignRole(ctx, c.Param("app_id"), c.Param("group_id"), req.RoleID)
+	if err != nil {
+		h.logger.Error(ctx, "assign group role failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "role_id", req.RoleID, "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusCreated)
+}
+
+func (h *GroupsHandler) RevokeRole(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.RevokeRole(ctx, c.Param("app_id"), c.Param("group_id"), c.Param("role_id"))
+	if err != nil {
+		h.logger.Error(ctx, "revoke group role failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "role_id", c.Param("role_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+func (h *GroupsHandler) AddMember(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		UserID string `json:"user_id"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for add group member", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	err := h.service.AddMember(ctx, c.Param("app_id"), c.Param("group_id"), req.UserID)
+	if err != nil {
+		h.logger.Error(ctx, "add group member failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "user_id", req.UserID, "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusCreated)
+}
+
+func (h *GroupsHandler) RemoveMember(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.RemoveMember(ctx, c.Param("app_id"), c.Param("group_id"), c.Param("user_id"))
+	if err != nil {
+		h.logger.Error(ctx, "remove group member failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "user_id", c.Param("user_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.NoContent(stdhttp.StatusNoContent)
+}
+
+func (h *GroupsHandler) ListMembers(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list group members", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	members, err := h.service.ListMembers(ctx, c.Param("app_id"), c.Param("group_id"), page)
+	if err != nil {
+		h.logger.Error(ctx, "list group members failed", "app_id", c.Param("app_id"), "group_id", c.Param("group_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, members)
+}
+
+type APIKeysHandler struct {
+	service *application.APIKeyService
+	logger  ports.Logger
+}
+
+func NewAPIKeysHandler(service *application.APIKeyService, logger ports.Logger) *APIKeysHandler {
+	return &APIKeysHandler{service: service, logger: logger}
+}
+
+func (h *APIKeysHandler) Create(c echo.Context) error {
+	ctx := c.Request().Context()
+	var req struct {
+		Name      string    `json:"name"`
+		AppIDs    []string  `json:"app_ids"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Warn(ctx, "invalid payload for create api key", "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid payload"})
+	}
+	issued, err := h.service.Create(ctx, domain.APIKey{Name: req.Name, AppIDs: req.AppIDs, ExpiresAt: req.ExpiresAt})
+	if err != nil {
+		h.logger.Error(ctx, "create api key failed", "name", req.Name, "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusCreated, issued)
+}
+
+func (h *APIKeysHandler) Get(c echo.Context) error {
+	ctx := c.Request().Context()
+	key, err := h.service.Get(ctx, c.Param("key_id"))
+	if err != nil {
+		h.logger.Error(ctx, "get api key failed", "key_id", c.Param("key_id"), "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, key)
+}
+
+func (h *APIKeysHandler) List(c echo.Context) error {
+	ctx := c.Request().Context()
+	page, err := pageRequest(c)
+	if err != nil {
+		h.logger.Warn(ctx, "invalid page for list api keys", "error", err)
+		return c.JSON(stdhttp.StatusBadRequest, map[string]string{"error": "invalid limit"})
+	}
+	keys, err := h.service.List(ctx, page)
+	if err != nil {
+		h.logger.Error(ctx, "list api keys failed", "error", err)
+		return handleError(c, err)
+	}
+	return c.JSON(stdhttp.StatusOK, keys)
+}
+
+func (h *APIKeysHandler) Revoke(c echo.Context) error {
+	ctx := c.Request().Context()
+	err := h.service.Revoke(ctx, c.Param("key_id"))
+	if err != nil {
+		h.logger.Error(ctx, "revoke api key failed", "key_id", c.Param("key_id"), "error", err)
 		return handleError(c, err)
 	}
-	return c.JSON(stdhttp.StatusOK, map[string]bool{"allowed": allowed})
+	return c.NoContent(stdhttp.StatusNoContent)
 }
@@ -0,0 +1,81 @@
+package http
+
+import (
+	stdhttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"rbac-project/internal/domain"
+)
+
+func TestForCaller(t *testing.T) {
+	claims := map[string]any{"sub": "u1"}
+	cases := []struct {
+		name       string
+		caller     string
+		userID     string
+		wantUserID string
+		wantClaims bool
+	}{
+		{"empty user is the caller", "u1", "", "u1", true},
+		{"caller's own check", "u1", "u1", "u1", true},
+		{"someone else's check", "u1", "u2", "u2", false},
+		{"unauthenticated", "", "u2", "u2", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := echo.New().NewContext(httptest.NewRequest(stdhttp.MethodPost, "/authorize", nil), httptest.NewRecorder())
+			if tc.caller != "" {
+				c.Set("user_id", tc.caller)
+				c.Set("claims", claims)
+			}
+			req := domain.AccessRequest{UserID: tc.userID}
+			forCaller(c, &req)
+			assert.Equal(t, tc.wantUserID, req.UserID)
+			if tc.wantClaims {
+				assert.Equal(t, claims, req.Claims)
+			} else {
+				assert.Nil(t, req.Claims)
+			}
+		})
+	}
+}
+
+func TestJSONWithETag(t *testing.T) {
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(stdhttp.MethodGet, "/", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		assert.NoError(t, jsonWithETag(echo.New().NewContext(req, rec), map[string]string{"id": "a"}))
+		return rec
+	}
+
+	first := get("")
+	etag := first.Header().Get("ETag")
+	assert.Equal(t, stdhttp.StatusOK, first.Code)
+	assert.NotEmpty(t, etag)
+	assert.JSONEq(t, `{"id":"a"}`, first.Body.String())
+
+	cases := []struct {
+		name        string
+		ifNoneMatch string
+		status      int
+	}{
+		{"matching", etag, stdhttp.StatusNotModified},
+		{"weak match", "W/" + etag, stdhttp.StatusNotModified},
+		{"one of several", `"other", ` + etag, stdhttp.StatusNotModified},
+		{"any", "*", stdhttp.StatusNotModified},
+		{"stale", `"other"`, stdhttp.StatusOK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := get(tc.ifNoneMatch)
+			assert.Equal(t, tc.status, rec.Code)
+			assert.Equal(t, etag, rec.Header().Get("ETag"))
+		})
+	}
+}
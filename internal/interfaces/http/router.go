@@ -5,12 +5,28 @@ import (
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"rbac-project/internal/domain"
 )
 
 type Middleware struct {
 	Auth          echo.MiddlewareFunc
 	XRay          echo.MiddlewareFunc
 	RequestLogger echo.MiddlewareFunc
+	Guard         *Guard
+}
+
+func (m Middleware) require(permission func(echo.Context) string) []echo.MiddlewareFunc {
+	if m.Guard == nil {
+		return nil
+	}
+	return []echo.MiddlewareFunc{m.Guard.Require(permission)}
+}
+
+func (m Middleware) requireUnlessCaller(permission func(echo.Context) string) []echo.MiddlewareFunc {
+	if m.Guard == nil {
+		return nil
+	}
+	return []echo.MiddlewareFunc{m.Guard.RequireUnlessCaller(permission)}
 }
 
 func newEcho(m Middleware) *echo.Echo {
@@ -32,37 +48,87 @@ func newEcho(m Middleware) *echo.Echo {
 
 func NewApplicationsRouter(h *ApplicationsHandler, m Middleware) *echo.Echo {
 	e := newEcho(m)
-	e.POST("/applications", h.Create)
-	e.PUT("/applications/:id", h.Update)
-	e.GET("/applications/:id", h.Get)
+	h.guard = m.Guard
+	e.POST("/applications", h.Create, m.require(permissionFor(domain.PermAppsWrite))...)
+	e.GET("/applications", h.List)
+	e.PUT("/applications/:id", h.Update, m.require(permissionFor(domain.PermAppsWrite))...)
+	e.GET("/applications/:id", h.Get, m.require(idPermission(domain.AppsReadPermission))...)
+	e.DELETE("/applications/:id", h.Delete, m.require(permissionFor(domain.PermAppsWrite))...)
 	return e
 }
 
 func NewRolesRouter(h *RolesHandler, m Middleware) *echo.Echo {
 	e := newEcho(m)
-	e.POST("/applications/:app_id/roles", h.Create)
-	e.PUT("/applications/:app_id/roles/:role_id", h.Update)
-	e.GET("/applications/:app_id/roles", h.List)
+	e.POST("/applications/:app_id/roles", h.Create, m.require(appPermission(domain.RolesWritePermission))...)
+	e.PUT("/applications/:app_id/roles/:role_id", h.Update, m.require(appPermission(domain.RolesWritePermission))...)
+	e.DELETE("/applications/:app_id/roles/:role_id", h.Delete, m.require(appPermission(domain.RolesWritePermission))...)
+	e.GET("/applications/:app_id/roles", h.List, m.require(appPermission(domain.RolesReadPermission))...)
+	e.GET("/applications/:app_id/roles/:role_id", h.Get, m.require(appPermission(domain.RolesReadPermission))...)
 	return e
 }
 
 func NewPermissionsRouter(h *PermissionsHandler, m Middleware) *echo.Echo {
 	e := newEcho(m)
-	e.POST("/applications/:app_id/permissions", h.Create)
-	e.GET("/applications/:app_id/permissions", h.List)
+	e.POST("/applications/:app_id/permissions", h.Create, m.require(appPermission(domain.RolesWritePermission))...)
+	e.PUT("/applications/:app_id/permissions/:permission_id", h.Update, m.require(appPermission(domain.RolesWritePermission))...)
+	e.DELETE("/applications/:app_id/permissions/:permission_id", h.Delete, m.require(appPermission(domain.RolesWritePermission))...)
+	e.GET("/applications/:app_id/permissions", h.List, m.require(appPermission(domain.RolesReadPermission))...)
 	return e
 }
 
 func NewUsersRouter(h *UsersHandler, m Middleware) *echo.Echo {
 	e := newEcho(m)
-	e.POST("/applications/:app_id/users/:user_id/roles", h.AssignRole)
-	e.GET("/applications/:app_id/users/:user_id", h.Get)
+	e.POST("/applications/:app_id/users/:user_id/roles", h.AssignRole, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.DELETE("/applications/:app_id/users/:user_id/roles/:role_id", h.RevokeRole, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.DELETE("/applications/:app_id/users/:user_id/roles", h.RevokeAllRoles, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.GET("/applications/:app_id/users/:user_id", h.Get, m.requireUnlessCaller(appPermission(domain.AssignmentsReadPermission))...)
+	e.GET("/applications/:app_id/users", h.ListByApp, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	e.GET("/applications/:app_id/roles/:role_id/users", h.ListByRole, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	e.GET("/users/:user_id/applications", h.ListApplications, m.requireUnlessCaller(permissionFor(domain.PermUsersRead))...)
 	return e
 }
 
 func NewAuthorizationRouter(h *AuthorizationHandler, m Middleware) *echo.Echo {
 	e := newEcho(m)
+	h.guard = m.Guard
 	e.POST("/authorize", h.Authorize)
+	e.POST("/authorize/batch", h.AuthorizeBatch)
+	e.POST("/authorize/explain", h.Explain)
+	e.GET("/applications/:app_id/users/:user_id/permissions", h.EffectivePermissions, m.requireUnlessCaller(appPermission(domain.AuthorizeOthersPermission))...)
+	return e
+}
+
+func NewGroupsRouter(h *GroupsHandler, m Middleware) *echo.Echo {
+	e := newEcho(m)
+	e.POST("/applications/:app_id/groups", h.Create, m.require(appPermission(domain.GroupsWritePermission))...)
+	e.GET("/applications/:app_id/groups", h.List, m.require(appPermission(domain.GroupsReadPermission))...)
+	e.PUT("/applications/:app_id/groups/:group_id", h.Update, m.require(appPermission(domain.GroupsWritePermission))...)
+	e.GET("/applications/:app_id/groups/:group_id", h.Get, m.require(appPermission(domain.GroupsReadPermission))...)
+	e.DELETE("/applications/:app_id/groups/:group_id", h.Delete, m.require(appPermission(domain.GroupsWritePermission))...)
+	e.POST("/applications/:app_id/groups/:group_id/roles", h.AssignRole, m.require(appPermission(domain.GroupsWritePermission))...)
+	e.DELETE("/applications/:app_id/groups/:group_id/roles/:role_id", h.RevokeRole, m.require(appPermission(domain.GroupsWritePermission))...)
+	e.POST("/applications/:app_id/groups/:group_id/members", h.AddMember, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.GET("/applications/:app_id/groups/:group_id/members", h.ListMembers, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	e.DELETE("/applications/:app_id/groups/:group_id/members/:user_id", h.RemoveMember, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	return e
+}
+
+func NewClaimMappingsRouter(h *ClaimMappingsHandler, m Middleware) *echo.Echo {
+	e := newEcho(m)
+	e.POST("/applications/:app_id/claim-mappings", h.Create, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.GET("/applications/:app_id/claim-mappings", h.List, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	e.PUT("/applications/:app_id/claim-mappings/:mapping_id", h.Update, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	e.GET("/applications/:app_id/claim-mappings/:mapping_id", h.Get, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	e.DELETE("/applications/:app_id/claim-mappings/:mapping_id", h.Delete, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	return e
+}
+
+func NewAPIKeysRouter(h *APIKeysHandler, m Middleware) *echo.Echo {
+	e := newEcho(m)
+	e.POST("/api-keys", h.Create, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	e.GET("/api-keys", h.List, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	e.GET("/api-keys/:key_id", h.Get, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	e.DELETE("/api-keys/:key_id", h.Revoke, m.require(permissionFor(domain.PermAPIKeysWrite))...)
 	return e
 }
 
@@ -72,6 +138,9 @@ func NewMainRouter(
 	permissions *PermissionsHandler,
 	users *UsersHandler,
 	authorization *AuthorizationHandler,
+	groups *GroupsHandler,
+	apiKeys *APIKeysHandler,
+	claimMappings *ClaimMappingsHandler,
 	m Middleware,
 ) *echo.Echo {
 	e := echo.New()
@@ -92,16 +161,51 @@ func NewMainRouter(
 	if m.Auth != nil {
 		api.Use(m.Auth)
 	}
-	api.POST("/applications", applications.Create)
-	api.PUT("/applications/:id", applications.Update)
-	api.GET("/applications/:id", applications.Get)
-	api.POST("/applications/:app_id/roles", roles.Create)
-	api.PUT("/applications/:app_id/roles/:role_id", roles.Update)
-	api.GET("/applications/:app_id/roles", roles.List)
-	api.POST("/applications/:app_id/permissions", permissions.Create)
-	api.GET("/applications/:app_id/permissions", permissions.List)
-	api.POST("/applications/:app_id/users/:user_id/roles", users.AssignRole)
-	api.GET("/applications/:app_id/users/:user_id", users.Get)
+	api.POST("/applications", applications.Create, m.require(permissionFor(domain.PermAppsWrite))...)
+	api.GET("/applications", applications.List)
+	api.PUT("/applications/:id", applications.Update, m.require(permissionFor(domain.PermAppsWrite))...)
+	api.GET("/applications/:id", applications.Get, m.require(idPermission(domain.AppsReadPermission))...)
+	api.DELETE("/applications/:id", applications.Delete, m.require(permissionFor(domain.PermAppsWrite))...)
+	api.POST("/applications/:app_id/roles", roles.Create, m.require(appPermission(domain.RolesWritePermission))...)
+	api.PUT("/applications/:app_id/roles/:role_id", roles.Update, m.require(appPermission(domain.RolesWritePermission))...)
+	api.DELETE("/applications/:app_id/roles/:role_id", roles.Delete, m.require(appPermission(domain.RolesWritePermission))...)
+	api.GET("/applications/:app_id/roles", roles.List, m.require(appPermission(domain.RolesReadPermission))...)
+	api.GET("/applications/:app_id/roles/:role_id", roles.Get, m.require(appPermission(domain.RolesReadPermission))...)
+	api.POST("/applications/:app_id/permissions", permissions.Create, m.require(appPermission(domain.RolesWritePermission))...)
+	api.PUT("/applications/:app_id/permissions/:permission_id", permissions.Update, m.require(appPermission(domain.RolesWritePermission))...)
+	api.DELETE("/applications/:app_id/permissions/:permission_id", permissions.Delete, m.require(appPermission(domain.RolesWritePermission))...)
+	api.GET("/applications/:app_id/permissions", permissions.List, m.require(appPermission(domain.RolesReadPermission))...)
+	api.POST("/applications/:app_id/users/:user_id/roles", users.AssignRole, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.DELETE("/applications/:app_id/users/:user_id/roles/:role_id", users.RevokeRole, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.DELETE("/applications/:app_id/users/:user_id/roles", users.RevokeAllRoles, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.GET("/applications/:app_id/users/:user_id", users.Get, m.requireUnlessCaller(appPermission(domain.AssignmentsReadPermission))...)
+	api.GET("/applications/:app_id/users", users.ListByApp, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	api.GET("/applications/:app_id/roles/:role_id/users", users.ListByRole, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	api.GET("/users/:user_id/applications", users.ListApplications, m.requireUnlessCaller(permissionFor(domain.PermUsersRead))...)
+	authorization.guard = m.Guard
+	applications.guard = m.Guard
 	api.POST("/authorize", authorization.Authorize)
+	api.POST("/authorize/batch", authorization.AuthorizeBatch)
+	api.POST("/authorize/explain", authorization.Explain)
+	api.GET("/applications/:app_id/users/:user_id/permissions", authorization.EffectivePermissions, m.requireUnlessCaller(appPermission(domain.AuthorizeOthersPermission))...)
+	api.POST("/applications/:app_id/groups", groups.Create, m.require(appPermission(domain.GroupsWritePermission))...)
+	api.GET("/applications/:app_id/groups", groups.List, m.require(appPermission(domain.GroupsReadPermission))...)
+	api.PUT("/applications/:app_id/groups/:group_id", groups.Update, m.require(appPermission(domain.GroupsWritePermission))...)
+	api.GET("/applications/:app_id/groups/:group_id", groups.Get, m.require(appPermission(domain.GroupsReadPermission))...)
+	api.DELETE("/applications/:app_id/groups/:group_id", groups.Delete, m.require(appPermission(domain.GroupsWritePermission))...)
+	api.POST("/applications/:app_id/groups/:group_id/roles", groups.AssignRole, m.require(appPermission(domain.GroupsWritePermission))...)
+	api.DELETE("/applications/:app_id/groups/:group_id/roles/:role_id", groups.RevokeRole, m.require(appPermission(domain.GroupsWritePermission))...)
+	api.POST("/applications/:app_id/groups/:group_id/members", groups.AddMember, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.GET("/applications/:app_id/groups/:group_id/members", groups.ListMembers, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	api.DELETE("/applications/:app_id/groups/:group_id/members/:user_id", groups.RemoveMember, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.POST("/applications/:app_id/claim-mappings", claimMappings.Create, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.GET("/applications/:app_id/claim-mappings", claimMappings.List, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	api.PUT("/applications/:app_id/claim-mappings/:mapping_id", claimMappings.Update, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.GET("/applications/:app_id/claim-mappings/:mapping_id", claimMappings.Get, m.require(appPermission(domain.AssignmentsReadPermission))...)
+	api.DELETE("/applications/:app_id/claim-mappings/:mapping_id", claimMappings.Delete, m.require(appPermission(domain.AssignmentsWritePermission))...)
+	api.POST("/api-keys", apiKeys.Create, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	api.GET("/api-keys", apiKeys.List, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	api.GET("/api-keys/:key_id", apiKeys.Get, m.require(permissionFor(domain.PermAPIKeysWrite))...)
+	api.DELETE("/api-keys/:key_id", apiKeys.Revoke, m.require(permissionFor(domain.PermAPIKeysWrite))...)
 	return e
 }
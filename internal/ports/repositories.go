@@ -3,26 +3,76 @@ package ports
 import (
 	"context"
 	"rbac-project/internal/domain"
+	"time"
 )
 
 type ApplicationRepository interface {
 	Create(ctx context.Context, app domain.Application) error
 	Update(ctx context.Context, app domain.Application) error
 	GetByID(ctx context.Context, appID string) (domain.Application, error)
+	List(ctx context.Context, query domain.ApplicationQuery) (domain.Page[domain.Application], error)
+	Delete(ctx context.Context, appID, nextToken string) (domain.ApplicationDeletion, error)
 }
 
 type RoleRepository interface {
 	Create(ctx context.Context, role domain.Role) error
-	Update(ctx context.Context, role domain.Role) error
+	Update(ctx context.Context, role domain.Role, unchanged []domain.Role) error
+	Delete(ctx context.Context, appID, roleID string) error
 	ListByAppID(ctx context.Context, appID string) ([]domain.Role, error)
+	ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Role], error)
 }
 
 type PermissionRepository interface {
 	Create(ctx context.Context, permission domain.Permission) error
+	Update(ctx context.Context, permission domain.Permission) error
+	Delete(ctx context.Context, appID, permissionID string) error
 	ListByAppID(ctx context.Context, appID string) ([]domain.Permission, error)
+	ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Permission], error)
 }
 
 type UserRoleRepository interface {
-	AssignRole(ctx context.Context, appID, userID, roleID string) error
+	AssignRole(ctx context.Context, appID, userID string, grant domain.RoleGrant) error
+	RevokeRole(ctx context.Context, appID, userID, roleID string) error
+	RevokeGrant(ctx context.Context, appID, userID string, grant domain.RoleGrant) error
+	RevokeSynced(ctx context.Context, appID, userID string, grant domain.RoleGrant) error
+	RevokeAllRoles(ctx context.Context, appID, userID string) error
+	PruneExpired(ctx context.Context, appID, userID string) error
 	GetByUserAndApp(ctx context.Context, appID, userID string) (domain.UserAppRoles, error)
+	ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error)
+	ListByUser(ctx context.Context, userID string, page domain.PageRequest) (domain.Page[domain.UserAppRoles], error)
+	ListByRole(ctx context.Context, appID, roleID string, page domain.PageRequest) (domain.Page[domain.RoleMember], error)
+}
+
+type GroupRepository interface {
+	Create(ctx context.Context, group domain.Group) error
+	Update(ctx context.Context, group domain.Group) error
+	Delete(ctx context.Context, appID, groupID string) error
+	Get(ctx context.Context, appID, groupID string) (domain.Group, error)
+	GetMany(ctx context.Context, appID string, groupIDs []string) ([]domain.Group, error)
+	ListByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.Group], error)
+	AssignRole(ctx context.Context, appID, groupID, roleID string) error
+	RevokeRole(ctx context.Context, appID, groupID, roleID string) error
+	AddMember(ctx context.Context, appID, groupID, userID string) error
+	RemoveMember(ctx context.Context, appID, groupID, userID string) error
+	ListMembers(ctx context.Context, appID, groupID string, page domain.PageRequest) (domain.Page[domain.GroupMember], error)
+}
+
+type ClaimMappingRepository interface {
+	Create(ctx context.Context, mapping domain.ClaimMapping) error
+	Update(ctx context.Context, mapping domain.ClaimMapping) error
+	Delete(ctx context.Context, appID, mappingID string) error
+	Get(ctx context.Context, appID, mappingID string) (domain.ClaimMapping, error)
+	ListByAppID(ctx context.Context, appID string) ([]domain.ClaimMapping, error)
+	ListPageByAppID(ctx context.Context, appID string, page domain.PageRequest) (domain.Page[domain.ClaimMapping], error)
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key domain.APIKey) error
+	Get(ctx context.Context, keyID string) (domain.APIKey, error)
+	List(ctx context.Context, page domain.PageRequest) (domain.Page[domain.APIKey], error)
+	Revoke(ctx context.Context, keyID string, at time.Time) error
+}
+
+type LeaseRepository interface {
+	Acquire(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
 }